@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// admonitionIcons holds the icon markup for each supported admonition type,
+// matching the styles in the app shell.
+var admonitionIcons = map[string]string{
+	"note":      `<i class="lni lni-notepad"></i>`,
+	"tip":       `<i class="lni lni-bulb"></i>`,
+	"important": `<i class="lni lni-bookmark"></i>`,
+	"warning":   `<i class="lni lni-warning"></i>`,
+	"caution":   `<i class="lni lni-ban"></i>`,
+}
+
+// admonitionAliases maps container names used by VuePress/Docusaurus content
+// onto our admonition types.
+var admonitionAliases = map[string]string{
+	"info":    "note",
+	"details": "note",
+	"danger":  "caution",
+}
+
+// admonitionOpen renders the opening markup of an admonition box
+func admonitionOpen(kind, title string) string {
+	if alias, ok := admonitionAliases[kind]; ok {
+		kind = alias
+	}
+	icon, ok := admonitionIcons[kind]
+	if !ok {
+		kind, icon = "note", admonitionIcons["note"]
+	}
+	return fmt.Sprintf(`<div class="admonition admonition-%s"><div class="admonition-title">%s %s</div><div>`, kind, icon, title)
+}
+
+const admonitionClose = "</div></div>\n"
+
+// KindContainer is the node kind of ::: container blocks
+var KindContainer = ast.NewNodeKind("Container")
+
+// Container is a `::: type Title` fenced block rendered as an admonition
+type Container struct {
+	ast.BaseBlock
+	AdmonitionType string
+	Title          string
+	fenceLength    int
+}
+
+func (n *Container) Kind() ast.NodeKind { return KindContainer }
+
+func (n *Container) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Type": n.AdmonitionType, "Title": n.Title}, nil)
+}
+
+type containerParser struct{}
+
+func (b *containerParser) Trigger() []byte { return []byte{':'} }
+
+func (b *containerParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, segment := reader.PeekLine()
+	pos := pc.BlockOffset()
+	if pos < 0 || line[pos] != ':' {
+		return nil, parser.NoChildren
+	}
+	i := pos
+	for ; i < len(line) && line[i] == ':'; i++ {
+	}
+	if i-pos < 3 {
+		return nil, parser.NoChildren
+	}
+	fields := strings.Fields(string(line[i:]))
+	if len(fields) == 0 {
+		return nil, parser.NoChildren // a bare fence only closes containers
+	}
+	kind := strings.ToLower(fields[0])
+	title := strings.Join(fields[1:], " ")
+	if title == "" {
+		title = strings.ToUpper(kind)
+	}
+	reader.Advance(segment.Len() - 1)
+	return &Container{AdmonitionType: kind, Title: title, fenceLength: i - pos}, parser.HasChildren
+}
+
+func (b *containerParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	line, segment := reader.PeekLine()
+	w, pos := util.IndentWidth(line, reader.LineOffset())
+	if w < 4 {
+		i := pos
+		for ; i < len(line) && line[i] == ':'; i++ {
+		}
+		if i-pos >= node.(*Container).fenceLength && util.IsBlank(line[i:]) {
+			reader.Advance(segment.Len() - 1)
+			return parser.Close
+		}
+	}
+	return parser.Continue | parser.HasChildren
+}
+
+func (b *containerParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {}
+
+func (b *containerParser) CanInterruptParagraph() bool { return true }
+
+func (b *containerParser) CanAcceptIndentedLine() bool { return false }
+
+type containerRenderer struct{}
+
+func (r *containerRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindContainer, func(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+		n := node.(*Container)
+		if entering {
+			_, _ = w.WriteString(admonitionOpen(n.AdmonitionType, html.EscapeString(n.Title)))
+		} else {
+			_, _ = w.WriteString(admonitionClose)
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+// containers is a goldmark extension for `::: type Title` ... `:::` blocks.
+// Nest containers by using a longer fence on the outer block.
+type containers struct{}
+
+func (e *containers) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithBlockParsers(util.Prioritized(&containerParser{}, 150)))
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(util.Prioritized(&containerRenderer{}, 500)))
+}
+
+// alertRegex matches the first line of a GitHub-style alert, `> [!NOTE] Title`
+var alertRegex = regexp.MustCompile(`^\[!(\w+)\]\s*(.*)$`)
+
+// alerts turns blockquotes starting with [!NOTE], [!TIP], [!IMPORTANT],
+// [!WARNING] or [!CAUTION] into admonitions, the rest of the first line
+// being the title
+type alerts struct{}
+
+func (e *alerts) Extend(m goldmark.Markdown) {
+	// Before the glossary and link transformers, which rewrite the title line
+	m.Parser().AddOptions(parser.WithASTTransformers(util.Prioritized(e, 700)))
+}
+
+func (e *alerts) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+	var quotes []*ast.Blockquote
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if q, ok := n.(*ast.Blockquote); ok && entering {
+			quotes = append(quotes, q)
+		}
+		return ast.WalkContinue, nil
+	})
+	for _, q := range quotes {
+		para, ok := q.FirstChild().(*ast.Paragraph)
+		if !ok || para.Lines().Len() == 0 {
+			continue
+		}
+		first := para.Lines().At(0)
+		m := alertRegex.FindSubmatch(bytes.TrimSpace(first.Value(source)))
+		if m == nil {
+			continue
+		}
+		kind := strings.ToLower(string(m[1]))
+		if _, ok := admonitionIcons[kind]; !ok {
+			continue
+		}
+		title := string(m[2])
+		if title == "" {
+			title = strings.ToUpper(kind)
+		}
+
+		// Drop the inlines of the first line, up to its line break
+		for child := para.FirstChild(); child != nil; {
+			next := child.NextSibling()
+			if inlineStart(child) >= first.Stop {
+				break
+			}
+			para.RemoveChild(para, child)
+			child = next
+		}
+		if !para.HasChildren() {
+			q.RemoveChild(q, para)
+		}
+
+		box := &Container{AdmonitionType: kind, Title: title}
+		for child := q.FirstChild(); child != nil; {
+			next := child.NextSibling()
+			box.AppendChild(box, child)
+			child = next
+		}
+		q.Parent().ReplaceChild(q.Parent(), q, box)
+	}
+}
+
+// inlineStart is the source offset of the first text of an inline node, or
+// -1 when it has none
+func inlineStart(n ast.Node) int {
+	switch n := n.(type) {
+	case *ast.Text:
+		return n.Segment.Start
+	case *ast.RawHTML:
+		if n.Segments.Len() > 0 {
+			return n.Segments.At(0).Start
+		}
+	}
+	for child := n.FirstChild(); child != nil; child = child.NextSibling() {
+		if start := inlineStart(child); start >= 0 {
+			return start
+		}
+	}
+	return -1
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"html"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// isAsset reports whether a file in the content folder is published as is:
+// anything but markdown, folder settings and hidden files.
+func isAsset(name string) bool {
+	return filepath.Ext(name) != ".md" && name != DirMetaFile && !strings.HasPrefix(name, ".")
+}
+
+// CopyAssets copies the files at rels (slash-separated, relative to
+// cfg.InputDir) to the same paths in the output folder.
+func CopyAssets(rels []string) error {
+	for _, rel := range rels {
+		if err := copyFile(contentFile(rel), outputFile(rel)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CopyStaticDir copies the whole static folder into the output folder; a
+// missing folder is skipped. It returns the number of files copied.
+func CopyStaticDir(dir string) (int, error) {
+	if _, err := os.Stat(dir); dir == "" || os.IsNotExist(err) {
+		return 0, nil
+	}
+	copied := 0
+	err := filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, p)
+		copied++
+		return copyFile(p, filepath.Join(cfg.OutputDir, rel))
+	})
+	return copied, err
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// resolveAssetLinks points relative links and images of a page to files next
+// to its source (dir, relative to cfg.InputDir) at their published path, so
+// ![img](./diagram.png) works wherever the page is shown. Links to anything
+// that is not an asset in the content folder are left alone.
+func resolveAssetLinks(content, dir string) string {
+	return urlAttrRegex.ReplaceAllStringFunc(content, func(m string) string {
+		parts := urlAttrRegex.FindStringSubmatch(m)
+		if strings.HasSuffix(parts[1], `srcset="`) {
+			return m
+		}
+		ref := html.UnescapeString(parts[2])
+		if link := assetLink(ref, dir); link != ref {
+			return parts[1] + html.EscapeString(link) + parts[3]
+		}
+		return m
+	})
+}
+
+// assetLink returns the published path of the asset the plain relative URL
+// ref points to from a page in dir, or ref itself when it is not one
+func assetLink(ref, dir string) string {
+	if ref == "" || strings.HasPrefix(ref, "/") || strings.HasPrefix(ref, "#") {
+		return ref
+	}
+	u, err := url.Parse(ref)
+	if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" {
+		return ref
+	}
+	rel := path.Join(dir, u.Path)
+	if strings.HasPrefix(rel, "../") || !isAsset(path.Base(rel)) {
+		return ref
+	}
+	if info, err := os.Stat(contentFile(rel)); err != nil || info.IsDir() {
+		return ref
+	}
+	u.Path = basePath() + "/" + diskCase(rel)
+	return u.String()
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"html"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// pageLinkRegex matches the targets of rendered content: link hrefs and
+// the pages of inlined {{ref:...}} sections
+var pageLinkRegex = regexp.MustCompile(`<a\s[^>]*?href="([^"]*)"|<div class="transclusion\b[^"]*" data-slug="([^"]*)"`)
+
+// linkedSlug returns the page a link on the page at from points to: app
+// routes (#/guide), site paths (/docs/guide) and relative markdown links
+// (../guide.md). Other links give "".
+func linkedSlug(from, href string, site *SiteData) string {
+	if strings.HasPrefix(href, "#/") {
+		href = href[1:]
+	} else if prefix := basePath() + "/"; strings.HasPrefix(href, prefix) {
+		href = href[len(prefix)-1:]
+	}
+	u, err := url.Parse(href)
+	if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" {
+		return ""
+	}
+	p := u.Path
+	if !strings.HasPrefix(p, "/") {
+		p = path.Join(path.Dir(from), p)
+	}
+	p = strings.TrimSuffix(strings.TrimSuffix(p, ".md"), "/index")
+	slug := redirectSlug(p)
+	if to, ok := site.Redirects[slug]; ok {
+		slug = to
+	}
+	if _, ok := site.Pages[slug]; !ok {
+		return ""
+	}
+	return slug
+}
+
+// Kinds of PageEdge
+const (
+	EdgeLink         = "link"
+	EdgeTransclusion = "transclusion"
+)
+
+// PageEdge is a link from a content page to another page
+type PageEdge struct {
+	From string `json:"source"`
+	To   string `json:"target"`
+	Kind string `json:"kind"`
+}
+
+// CollectLinks lists the links between pages found in the rendered content
+// of content pages: wiki links, refs and markdown links. Generated pages
+// (listings, indexes) are not counted as sources. Each link appears once,
+// sorted.
+func CollectLinks(site *SiteData) []PageEdge {
+	seen := make(map[PageEdge]bool)
+	var edges []PageEdge
+	for from, page := range site.Pages {
+		if page.ModTime.IsZero() {
+			continue
+		}
+		for _, m := range pageLinkRegex.FindAllStringSubmatch(page.Content, -1) {
+			edge := PageEdge{From: from, To: html.UnescapeString(m[2]), Kind: EdgeTransclusion}
+			if m[1] != "" {
+				edge.To, edge.Kind = linkedSlug(from, html.UnescapeString(m[1]), site), EdgeLink
+			}
+			if edge.To == "" || edge.To == from || seen[edge] {
+				continue
+			}
+			seen[edge] = true
+			edges = append(edges, edge)
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		a, b := edges[i], edges[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		if a.To != b.To {
+			return a.To < b.To
+		}
+		return a.Kind < b.Kind
+	})
+	return edges
+}
+
+// ComputeBacklinks fills PageData.Backlinks with the pages linking to each
+// page
+func ComputeBacklinks(pages map[string]PageData, edges []PageEdge) {
+	linkedFrom := make(map[string]map[string]bool)
+	for _, e := range edges {
+		if linkedFrom[e.To] == nil {
+			linkedFrom[e.To] = make(map[string]bool)
+		}
+		linkedFrom[e.To][e.From] = true
+	}
+	for to, froms := range linkedFrom {
+		page, ok := pages[to]
+		if !ok {
+			continue
+		}
+		page.Backlinks = nil
+		for from := range froms {
+			page.Backlinks = append(page.Backlinks, PageLink{Title: pages[from].Title, Slug: from})
+		}
+		sort.Slice(page.Backlinks, func(i, j int) bool {
+			a, b := page.Backlinks[i], page.Backlinks[j]
+			if a.Title != b.Title {
+				return a.Title < b.Title
+			}
+			return a.Slug < b.Slug
+		})
+		pages[to] = page
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+// AddBreadcrumbs sets the Breadcrumbs of the pages in the menu: Home and
+// the folders above the page, linking to the index pages of the folders
+// that have one
+func AddBreadcrumbs(pages map[string]PageData, menu []*MenuItem) {
+	for slug, page := range pages {
+		page.Breadcrumbs = nil
+		trail := menuTrail(menu, slug)
+		if slug != "/" && trail != nil {
+			page.Breadcrumbs = []PageLink{{Title: "Home", Slug: "/"}}
+			for _, item := range trail[:len(trail)-1] {
+				page.Breadcrumbs = append(page.Breadcrumbs, PageLink{Title: item.Title, Slug: item.Slug})
+			}
+		}
+		pages[slug] = page
+	}
+}
+
+// menuTrail returns the menu items leading to the page at slug, the page
+// itself last, or nil when it is not in the menu.
+func menuTrail(items []*MenuItem, slug string) []*MenuItem {
+	for _, item := range items {
+		if item.Slug == slug && !item.IsFolder {
+			return []*MenuItem{item}
+		}
+		if trail := menuTrail(item.Children, slug); trail != nil {
+			return append([]*MenuItem{item}, trail...)
+		}
+		if item.Slug == slug {
+			return []*MenuItem{item} // folder landing page
+		}
+	}
+	return nil
+}
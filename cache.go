@@ -0,0 +1,168 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// BuildCache keeps the rendered result of every markdown file keyed by its
+// content hash, so a build only runs ProcessMarkdown for files that changed
+// (or whose includes changed). The cache is dropped whenever the settings,
+// glossary, shortcode templates or generator version differ from the build
+// that wrote it.
+type BuildCache struct {
+	Env   string                `json:"env"`
+	Files map[string]cachedFile `json:"files"`
+
+	path   string
+	seen   map[string]bool
+	reused int
+	dirty  bool
+}
+
+type cachedFile struct {
+	Hash     string            `json:"hash"`
+	Includes map[string]string `json:"includes,omitempty"` // include path -> hash
+	Result   *RenderResult     `json:"result"`
+}
+
+// NewBuildCache loads the cache at path; an empty path disables caching
+func NewBuildCache(path string) *BuildCache {
+	if path == "" {
+		return nil
+	}
+	c := &BuildCache{Env: buildEnv(), Files: make(map[string]cachedFile), path: path, seen: make(map[string]bool)}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return c
+	}
+	var stored BuildCache
+	if err := json.Unmarshal(data, &stored); err != nil {
+		fmt.Println("Warning: ignoring corrupt build cache:", err)
+		return c
+	}
+	if stored.Env == c.Env {
+		c.Files = stored.Files
+	}
+	return c
+}
+
+// buildEnv hashes everything besides the file itself that affects rendering
+func buildEnv() string {
+	h := sha256.New()
+	c := cfg
+	c.OutputDir = "" // serve builds into a temp folder with the same pages
+	c.Drafts, c.Future = false, false
+	settings, _ := json.Marshal(c)
+	h.Write([]byte(version))
+	h.Write(settings)
+	glossary, _ := os.ReadFile(cfg.GlossaryFile)
+	h.Write(glossary)
+	h.Write(shortcodeSources)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func hashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Render returns the cached result for the file at rel (relative to
+// cfg.InputDir) if it is still current, otherwise renders it.
+func (c *BuildCache) Render(rel string, source []byte) (*RenderResult, error) {
+	if c == nil {
+		return renderIsolated(source)
+	}
+	c.seen[rel] = true
+	hash := hashBytes(source)
+	if cached, ok := c.Files[rel]; ok && cached.Hash == hash && includesCurrent(cached.Includes) {
+		c.reused++
+		result := *cached.Result
+		result.Meta, _ = jsonValue(cached.Result.Meta).(map[string]interface{}) // callers may modify it
+		return &result, nil
+	}
+
+	result, err := renderIsolated(source)
+	if err != nil || len(result.Warnings) > 0 {
+		// Files with problems (e.g. a missing include) are rendered every time
+		if _, ok := c.Files[rel]; ok {
+			delete(c.Files, rel)
+			c.dirty = true
+		}
+		return result, err
+	}
+	entry := cachedFile{Hash: hash, Includes: make(map[string]string), Result: result}
+	for _, inc := range result.Includes {
+		data, _ := os.ReadFile(contentFile(inc))
+		entry.Includes[inc] = hashBytes(data)
+	}
+	// Store the metadata the way it reads back from JSON
+	stored := *result
+	stored.Meta, _ = jsonValue(result.Meta).(map[string]interface{})
+	entry.Result = &stored
+	c.Files[rel] = entry
+	c.dirty = true
+	return result, nil
+}
+
+func includesCurrent(includes map[string]string) bool {
+	for inc, hash := range includes {
+		data, err := os.ReadFile(contentFile(inc))
+		if err != nil || hashBytes(data) != hash {
+			return false
+		}
+	}
+	return true
+}
+
+// jsonValue converts YAML maps (map[interface{}]interface{}) to
+// map[string]interface{} so frontmatter can be stored as JSON
+func jsonValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, val := range t {
+			m[fmt.Sprintf("%v", k)] = jsonValue(val)
+		}
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, val := range t {
+			m[k] = jsonValue(val)
+		}
+		return m
+	case []interface{}:
+		list := make([]interface{}, len(t))
+		for i, val := range t {
+			list[i] = jsonValue(val)
+		}
+		return list
+	}
+	return v
+}
+
+// Save writes the cache, dropping files that no longer exist
+func (c *BuildCache) Save() error {
+	if c == nil {
+		return nil
+	}
+	for rel := range c.Files {
+		if !c.seen[rel] {
+			delete(c.Files, rel)
+			c.dirty = true
+		}
+	}
+	fmt.Printf("Rendered %d files, %d unchanged\n", len(c.seen)-c.reused, c.reused)
+	if !c.dirty {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	data, _ := json.Marshal(c)
+	return os.WriteFile(c.path, data, 0644)
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CatalogSlug is the route of the generated "All guides" page
+const CatalogSlug = "/all-guides"
+
+// Facets of catalog.json, in the order the "All guides" page offers them
+const (
+	FacetType     = "type"
+	FacetTags     = "tags"
+	FacetPlatform = "platform"
+	FacetUpdated  = "updated"
+)
+
+var catalogFacets = []string{FacetType, FacetTags, FacetPlatform, FacetUpdated}
+
+// Catalog is catalog.json: every content page with its facet values, and
+// the values of each facet with the number of pages having them
+type Catalog struct {
+	Facets  map[string][]CatalogFacet `json:"facets"`
+	Entries []CatalogEntry            `json:"entries"`
+}
+
+// CatalogFacet is one value of a facet. Value is normalized (see termSlug;
+// "2024-05" for updated), Label is how it is shown.
+type CatalogFacet struct {
+	Value string `json:"value"`
+	Label string `json:"label"`
+	Count int    `json:"count"`
+}
+
+// CatalogEntry is a page of the catalog; its facet fields hold normalized values
+type CatalogEntry struct {
+	Slug        string   `json:"slug"`
+	Title       string   `json:"title"`
+	Description string   `json:"description,omitempty"`
+	Type        string   `json:"type,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Platform    []string `json:"platform,omitempty"`
+	Updated     string   `json:"updated,omitempty"` // 2006-01-02
+}
+
+// pagePlatforms reads the platform of a page, from the custom taxonomy of
+// that name when configured, otherwise from `platform` frontmatter
+func pagePlatforms(page PageData) []string {
+	if _, ok := cfg.Taxonomies[FacetPlatform]; ok {
+		return page.Terms[FacetPlatform]
+	}
+	return parseTerms(page.Extra[FacetPlatform])
+}
+
+// pageUpdated returns when a page last changed: its updated date, else
+// its published date, else the modification time of its source
+func pageUpdated(page PageData) time.Time {
+	switch {
+	case !page.UpdatedAt.IsZero():
+		return page.UpdatedAt
+	case !page.PublishedAt.IsZero():
+		return page.PublishedAt
+	}
+	return page.ModTime
+}
+
+// BuildCatalog lists the indexed content pages by title. Generated pages
+// (no source file) and posts listing pages are left out.
+func BuildCatalog(pages map[string]PageData) Catalog {
+	facets := make(map[string]map[string]*CatalogFacet, len(catalogFacets))
+	for _, name := range catalogFacets {
+		facets[name] = make(map[string]*CatalogFacet)
+	}
+	add := func(facet, label, value string) string {
+		f := facets[facet][value]
+		if f == nil {
+			f = &CatalogFacet{Value: value, Label: label}
+			facets[facet][value] = f
+		}
+		f.Count++
+		return value
+	}
+	addTerms := func(facet string, terms []string) []string {
+		var values []string
+		seen := make(map[string]bool)
+		for _, term := range terms {
+			value := termSlug(term)
+			if value == "" || seen[value] {
+				continue
+			}
+			seen[value] = true
+			values = append(values, add(facet, term, value))
+		}
+		return values
+	}
+
+	catalog := Catalog{Facets: make(map[string][]CatalogFacet), Entries: []CatalogEntry{}}
+	for slug, page := range pages {
+		if page.ModTime.IsZero() || !page.Indexed() || page.ListPage > 0 {
+			continue
+		}
+		entry := CatalogEntry{Slug: slug, Title: page.Title, Description: page.Description}
+		if types := addTerms(FacetType, []string{page.Type}); len(types) > 0 {
+			entry.Type = types[0]
+		}
+		entry.Tags = addTerms(FacetTags, page.Tags)
+		entry.Platform = addTerms(FacetPlatform, pagePlatforms(page))
+		if updated := pageUpdated(page); !updated.IsZero() {
+			entry.Updated = updated.Format("2006-01-02")
+			add(FacetUpdated, updated.Format("January 2006"), updated.Format("2006-01"))
+		}
+		catalog.Entries = append(catalog.Entries, entry)
+	}
+	sort.Slice(catalog.Entries, func(i, j int) bool {
+		a, b := catalog.Entries[i], catalog.Entries[j]
+		if !strings.EqualFold(a.Title, b.Title) {
+			return strings.ToLower(a.Title) < strings.ToLower(b.Title)
+		}
+		return a.Slug < b.Slug
+	})
+
+	for _, name := range catalogFacets {
+		values := make([]CatalogFacet, 0, len(facets[name]))
+		for _, f := range facets[name] {
+			values = append(values, *f)
+		}
+		sort.Slice(values, func(i, j int) bool {
+			if name == FacetUpdated {
+				return values[i].Value > values[j].Value
+			}
+			if values[i].Count != values[j].Count {
+				return values[i].Count > values[j].Count
+			}
+			return values[i].Value < values[j].Value
+		})
+		catalog.Facets[name] = values
+	}
+	return catalog
+}
+
+// WriteCatalog writes catalog.json to the output folder
+func WriteCatalog(catalog Catalog, assets AssetManifest) error {
+	data, _ := json.Marshal(catalog)
+	return assets.WriteFile("catalog.json", data)
+}
+
+// BuildCatalogPage lists every guide of the catalog. The app shell adds
+// the facet filters over catalog.json; static outputs show the plain list.
+func BuildCatalogPage(catalog Catalog) PageData {
+	var buf strings.Builder
+	buf.WriteString(fmt.Sprintf("<p>%d guides.</p>\n<ul>\n", len(catalog.Entries)))
+	for _, e := range catalog.Entries {
+		buf.WriteString(fmt.Sprintf("<li><a href=\"%s\">%s</a>", pageHref(e.Slug), html.EscapeString(e.Title)))
+		if e.Description != "" {
+			buf.WriteString("<br>" + html.EscapeString(e.Description))
+		}
+		buf.WriteString("</li>\n")
+	}
+	buf.WriteString("</ul>\n")
+	return PageData{
+		Title:       "All Guides",
+		Content:     buf.String(),
+		Description: "Every guide, filterable by type, tags, platform and last update.",
+		HideTOC:     true,
+		HideMeta:    true,
+		Catalog:     true,
+	}
+}
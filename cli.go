@@ -0,0 +1,261 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// version is set at release time with -ldflags "-X main.version=..."
+var version = "dev"
+
+const usage = `Usage: go-blog <command> [flags]
+
+Commands:
+  build     generate the site (default)
+  serve     build and serve the site locally
+  new       create a content file, e.g. new vps/backups.md
+  clean     remove the output folder
+  version   print the version
+
+Run go-blog <command> -h for the flags of a command.
+`
+
+func main() {
+	args := os.Args[1:]
+	cmd := "build"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
+	}
+
+	var err error
+	switch cmd {
+	case "build":
+		err = runBuild(args)
+	case "serve":
+		err = runServe(args)
+	case "new":
+		err = runNew(args)
+	case "clean":
+		err = runClean(args)
+	case "version":
+		fmt.Println("go-blog", version)
+	case "help":
+		fmt.Print(usage)
+	default:
+		fmt.Printf("Unknown command %q\n\n%s", cmd, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+}
+
+// configFlags registers the flags shared by all commands that read the
+// config; the returned function loads it into cfg after parsing.
+func configFlags(fs *flag.FlagSet) func() error {
+	configFile := fs.String("c", DefaultConfigFile, "config file")
+	inputDir := fs.String("input", "", "content folder (overrides input_dir)")
+	outputDir := fs.String("output", "", "output folder (overrides output_dir)")
+	baseURL := fs.String("base-url", "", "site URL (overrides base_url)")
+	return func() error {
+		configRequired := false
+		fs.Visit(func(f *flag.Flag) { configRequired = configRequired || f.Name == "c" })
+		c, err := LoadConfig(*configFile, configRequired)
+		if err != nil {
+			return fmt.Errorf("loading config: %w", err)
+		}
+		if *inputDir != "" {
+			c.InputDir = *inputDir
+		}
+		if *outputDir != "" {
+			c.OutputDir = *outputDir
+		}
+		if *baseURL != "" {
+			c.BaseURL = *baseURL
+		}
+		cfg = c
+		initMarkdown()
+		return nil
+	}
+}
+
+func runBuild(args []string) error {
+	fs := flag.NewFlagSet("build", flag.ExitOnError)
+	load := configFlags(fs)
+	noCache := fs.Bool("no-cache", false, "render every file, ignoring the build cache")
+	drafts := fs.Bool("drafts", false, "include draft pages")
+	future := fs.Bool("future", false, "include pages published in the future")
+	releaseAt := fs.String("release-at", "", "build the site as of this `time`, e.g. \"2026-03-01 09:00\"")
+	prerender := fs.Bool("prerender", false, "also write static HTML for every page")
+	validateOutput := fs.Bool("validate-output", false, "check db.json against its JSON Schema")
+	pretty := fs.Bool("pretty", false, "write indented db.json and unminified HTML")
+	strict := fs.Bool("strict", false, "fail on broken links")
+	fs.Parse(args)
+	if err := load(); err != nil {
+		return err
+	}
+	cfg.Prerender = cfg.Prerender || *prerender
+	cfg.ValidateOutput = cfg.ValidateOutput || *validateOutput
+	cfg.Pretty = cfg.Pretty || *pretty
+	cfg.Strict = cfg.Strict || *strict
+	if *noCache {
+		cfg.BuildCacheFile = ""
+	}
+	cfg.Drafts = cfg.Drafts || *drafts
+	cfg.Future = cfg.Future || *future
+	if *releaseAt != "" {
+		if _, ok := parseDate(*releaseAt); !ok {
+			return fmt.Errorf("--release-at: unrecognized date %q", *releaseAt)
+		}
+		cfg.ReleaseAt = *releaseAt
+	}
+	return build()
+}
+
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	load := configFlags(fs)
+	addr := fs.String("addr", "localhost:1313", "address to listen on")
+	watch := fs.Bool("watch", true, "rebuild and reload the browser on changes")
+	drafts := fs.Bool("drafts", false, "include draft pages")
+	future := fs.Bool("future", false, "include pages published in the future")
+	fs.Parse(args)
+	if err := load(); err != nil {
+		return err
+	}
+	cfg.Drafts = cfg.Drafts || *drafts
+	cfg.Future = cfg.Future || *future
+
+	// Build into a temp folder so the published output stays untouched
+	dir, err := os.MkdirTemp("", "go-blog-serve-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	cfg.OutputDir = dir
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-interrupt
+		os.RemoveAll(dir)
+		os.Exit(0)
+	}()
+
+	if *watch {
+		watchedPages = make(map[string]string)
+	}
+	if err := build(); err != nil {
+		return err
+	}
+	handler := siteHandler()
+	if *watch {
+		rl := newReloader()
+		watcher, err := watchSite(rl.Reload)
+		if err != nil {
+			return err
+		}
+		defer watcher.Close()
+		handler = withLiveReload(handler, rl)
+	}
+	fmt.Printf("Serving on http://%s%s/\n", *addr, basePath())
+	return http.ListenAndServe(*addr, handler)
+}
+
+// siteHandler serves the output folder under the base path. With path
+// routing, unknown paths fall back to the app shell like a host rewrite.
+func siteHandler() http.Handler {
+	files := http.FileServer(http.Dir(cfg.OutputDir))
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if cfg.URLRouting == RoutingPath && cfg.Profile == ProfileSPA {
+			// Folders holding only feeds or assets are routes of the app too
+			path := outputFile(r.URL.Path)
+			info, err := os.Stat(path)
+			if err == nil && info.IsDir() {
+				_, err = os.Stat(filepath.Join(path, "index.html"))
+			}
+			if err != nil {
+				http.ServeFile(w, r, filepath.Join(cfg.OutputDir, "index.html"))
+				return
+			}
+		}
+		files.ServeHTTP(w, r)
+	})
+	if base := basePath(); base != "" {
+		return http.StripPrefix(base, handler)
+	}
+	return handler
+}
+
+func runNew(args []string) error {
+	fs := flag.NewFlagSet("new", flag.ExitOnError)
+	load := configFlags(fs)
+	fs.Parse(args)
+	if err := load(); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: go-blog new <path>.md")
+	}
+	rel := slashPath(fs.Arg(0))
+	if rel == ".." || strings.HasPrefix(rel, "../") || strings.HasPrefix(rel, "/") || filepath.VolumeName(rel) != "" {
+		return fmt.Errorf("%s is outside %s", fs.Arg(0), cfg.InputDir)
+	}
+	if filepath.Ext(rel) != ".md" {
+		rel += ".md"
+	}
+	path := contentFile(rel)
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("%s already exists", path)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	today := time.Now().Format("2006-01-02")
+	title := titleCase(strings.TrimSuffix(filepath.Base(path), ".md"))
+	quoted, err := yaml.Marshal(title) // file names may hold ':', '#' and the like
+	if err != nil {
+		return err
+	}
+	content := fmt.Sprintf("---\ntitle: %spublished on: %s\nupdated on: %s\n---\n\n", quoted, today, today)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return err
+	}
+	fmt.Println("Created", path)
+	return nil
+}
+
+func runClean(args []string) error {
+	fs := flag.NewFlagSet("clean", flag.ExitOnError)
+	load := configFlags(fs)
+	cache := fs.Bool("cache", false, "also remove the build and summary caches and the change history")
+	fs.Parse(args)
+	if err := load(); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(cfg.OutputDir); err != nil {
+		return err
+	}
+	fmt.Println("Removed", cfg.OutputDir)
+	if *cache {
+		for _, file := range []string{cfg.BuildCacheFile, cfg.SummaryCacheFile, cfg.ChangeHistoryFile} {
+			if file == "" {
+				continue
+			}
+			if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			fmt.Println("Removed", file)
+		}
+	}
+	return nil
+}
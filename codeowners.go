@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// codeownersPaths are the places GitHub and GitLab look for CODEOWNERS
+var codeownersPaths = []string{"CODEOWNERS", ".github/CODEOWNERS", "docs/CODEOWNERS", ".gitlab/CODEOWNERS"}
+
+// ownerRule is one CODEOWNERS line
+type ownerRule struct {
+	pattern *regexp.Regexp
+	owners  []string
+}
+
+// CodeOwners maps file paths to owners; the last matching rule wins
+type CodeOwners []ownerRule
+
+// LoadCodeOwners reads the CODEOWNERS file at path, or from the standard
+// locations when path is empty. It returns nil when there is none.
+func LoadCodeOwners(path string) (CodeOwners, error) {
+	if path == "" {
+		for _, p := range codeownersPaths {
+			if _, err := os.Stat(p); err == nil {
+				path = p
+				break
+			}
+		}
+		if path == "" {
+			return nil, nil
+		}
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var rules CodeOwners
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "[") {
+			continue // blank, comment or GitLab section header
+		}
+		if i := strings.Index(line, " #"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		rules = append(rules, ownerRule{pattern: codeownersPattern(fields[0]), owners: fields[1:]})
+	}
+	return rules, scanner.Err()
+}
+
+// codeownersPattern compiles a gitignore style pattern: "/docs/" is
+// anchored to the repository root, "*.md" matches at any depth and "**"
+// spans folders.
+func codeownersPattern(p string) *regexp.Regexp {
+	anchored := strings.HasPrefix(p, "/") || strings.Contains(strings.TrimSuffix(p, "/"), "/")
+	p = strings.TrimPrefix(p, "/")
+	dir := strings.HasSuffix(p, "/")
+	p = strings.TrimSuffix(p, "/")
+
+	var re strings.Builder
+	if anchored {
+		re.WriteString("^")
+	} else {
+		re.WriteString("(^|/)")
+	}
+	for i := 0; i < len(p); i++ {
+		switch {
+		case strings.HasPrefix(p[i:], "**/"):
+			re.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(p[i:], "**"):
+			re.WriteString(".*")
+			i++
+		case p[i] == '*':
+			re.WriteString("[^/]*")
+		case p[i] == '?':
+			re.WriteString("[^/]")
+		default:
+			re.WriteString(regexp.QuoteMeta(p[i : i+1]))
+		}
+	}
+	if dir {
+		re.WriteString("/")
+	} else {
+		re.WriteString("(/|$)")
+	}
+	return regexp.MustCompile(re.String())
+}
+
+// Owners returns the owners of a file path relative to the repository root
+func (c CodeOwners) Owners(path string) []string {
+	if filepath.IsAbs(path) {
+		if wd, err := os.Getwd(); err == nil {
+			if rel, err := filepath.Rel(wd, path); err == nil {
+				path = rel
+			}
+		}
+	}
+	path = filepath.ToSlash(filepath.Clean(path))
+	for i := len(c) - 1; i >= 0; i-- {
+		if c[i].pattern.MatchString(path) {
+			return c[i].owners
+		}
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"regexp"
+)
+
+// markdownCode returns the byte ranges of the fenced code blocks and code
+// spans of markdown source, where include tags and shortcodes are shown as
+// written rather than expanded. Indented code blocks are not detected.
+func markdownCode(source []byte) [][2]int {
+	var code [][2]int
+	var fence []byte // opening fence of the block being read
+	start, text := 0, 0
+	for pos := 0; pos < len(source); {
+		end := bytes.IndexByte(source[pos:], '\n')
+		if end < 0 {
+			end = len(source)
+		} else {
+			end += pos + 1
+		}
+		line := bytes.TrimRight(source[pos:end], "\r\n")
+		trimmed := bytes.TrimLeft(line, " ")
+		if len(line)-len(trimmed) < 4 {
+			if fence == nil {
+				if fence = openingFence(trimmed); fence != nil {
+					code = append(code, codeSpans(source, text, pos)...)
+					start = pos
+				}
+			} else if closesFence(trimmed, fence) {
+				code = append(code, [2]int{start, end})
+				fence, text = nil, end
+			}
+		}
+		pos = end
+	}
+	if fence != nil {
+		return append(code, [2]int{start, len(source)}) // runs to the end of the document
+	}
+	return append(code, codeSpans(source, text, len(source))...)
+}
+
+// openingFence returns the ``` or ~~~ run opening a fenced code block on
+// line, or nil
+func openingFence(line []byte) []byte {
+	if len(line) == 0 || (line[0] != '`' && line[0] != '~') {
+		return nil
+	}
+	n := 0
+	for n < len(line) && line[n] == line[0] {
+		n++
+	}
+	if n < 3 || (line[0] == '`' && bytes.IndexByte(line[n:], '`') >= 0) {
+		return nil
+	}
+	return line[:n]
+}
+
+// closesFence reports whether line closes the block opened by fence: a run
+// of the same character, at least as long, and nothing else
+func closesFence(line, fence []byte) bool {
+	line = bytes.TrimRight(line, " \t")
+	return len(line) >= len(fence) && len(bytes.Trim(line, string(fence[:1]))) == 0
+}
+
+// codeSpans returns the ranges of the `code` spans of source[from:to]: a run
+// of backticks up to the next run of the same length
+func codeSpans(source []byte, from, to int) [][2]int {
+	var spans [][2]int
+	for i := from; i < to; {
+		if source[i] != '`' {
+			i++
+			continue
+		}
+		n := backtickRun(source[i:to])
+		if i > 0 && source[i-1] == '\\' { // an escaped backtick opens nothing
+			i += n
+			continue
+		}
+		closed := false
+		for j := i + n; j < to; {
+			if source[j] != '`' {
+				j++
+				continue
+			}
+			m := backtickRun(source[j:to])
+			if m == n {
+				spans = append(spans, [2]int{i, j + m})
+				i, closed = j+m, true
+				break
+			}
+			j += m
+		}
+		if !closed {
+			i += n
+		}
+	}
+	return spans
+}
+
+// backtickRun returns the number of backticks data starts with
+func backtickRun(data []byte) int {
+	n := 0
+	for n < len(data) && data[n] == '`' {
+		n++
+	}
+	return n
+}
+
+// codeEnd returns the end of the code range of code holding offset i
+func codeEnd(code [][2]int, i int) (int, bool) {
+	for _, c := range code {
+		if i >= c[0] && i < c[1] {
+			return c[1], true
+		}
+	}
+	return 0, false
+}
+
+// replaceOutsideCode replaces the matches of re in markdown source with fn,
+// leaving fenced code blocks and code spans alone
+func replaceOutsideCode(source []byte, re *regexp.Regexp, fn func([]byte) []byte) []byte {
+	var buf bytes.Buffer
+	last := 0
+	for _, c := range markdownCode(source) {
+		buf.Write(re.ReplaceAllFunc(source[last:c[0]], fn))
+		buf.Write(source[c[0]:c[1]])
+		last = c[1]
+	}
+	buf.Write(re.ReplaceAllFunc(source[last:], fn))
+	return buf.Bytes()
+}
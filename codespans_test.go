@@ -0,0 +1,31 @@
+package main
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestReplaceOutsideCode(t *testing.T) {
+	tag := regexp.MustCompile(`\{\{x\}\}`)
+	tests := map[string]string{
+		"a {{x}} b":                        "a X b",
+		"`{{x}}` {{x}}":                    "`{{x}}` X",
+		"``a ` {{x}}`` {{x}}":              "``a ` {{x}}`` X",
+		"\\`{{x}}`":                        "\\`X`",
+		"unclosed ` {{x}}":                 "unclosed ` X",
+		"```\n{{x}}\n```\n{{x}}":           "```\n{{x}}\n```\nX",
+		"```go\n{{x}}\n```\r\n{{x}}":       "```go\n{{x}}\n```\r\nX",
+		"~~~~\n~~~\n{{x}}\n~~~~\n{{x}}":    "~~~~\n~~~\n{{x}}\n~~~~\nX",
+		"  ```\n{{x}}\n  ```\n{{x}}":       "  ```\n{{x}}\n  ```\nX",
+		"``` a`b\n{{x}}":                   "``` a`b\nX",   // not a fence
+		"```\n{{x}}\n":                     "```\n{{x}}\n", // open to the end
+		"    ```\n{{x}}":                   "    ```\nX",   // indented, not a fence
+		"```\n{{x}}\n```` `\n{{x}}\n```\n": "```\n{{x}}\n```` `\n{{x}}\n```\n",
+	}
+	for in, want := range tests {
+		got := string(replaceOutsideCode([]byte(in), tag, func([]byte) []byte { return []byte("X") }))
+		if got != want {
+			t.Errorf("replaceOutsideCode(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
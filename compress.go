@@ -0,0 +1,71 @@
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// precompressedExts are the output files given .gz and .br siblings
+var precompressedExts = map[string]bool{".html": true, ".json": true, ".xml": true}
+
+// Precompress writes name.gz and name.br next to every HTML, JSON and XML
+// file in the output folder, running cfg.BrotliCommand for the .br copy
+// when set. It returns the number of files compressed.
+func Precompress() (int, error) {
+	count := 0
+	err := filepath.WalkDir(cfg.OutputDir, func(p string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !precompressedExts[strings.ToLower(filepath.Ext(p))] {
+			return err
+		}
+		gz := func(w io.Writer) io.WriteCloser {
+			zw, _ := gzip.NewWriterLevel(w, cfg.GzipLevel)
+			return zw
+		}
+		if err := compressFile(p, ".gz", gz); err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+		if cfg.BrotliCommand != "" {
+			placeholders := strings.NewReplacer("{in}", p, "{out}", p+".br", "{level}", strconv.Itoa(cfg.BrotliLevel))
+			if err := runCommand(cfg.BrotliCommand, placeholders); err != nil {
+				return fmt.Errorf("%s: brotli: %w", p, err)
+			}
+		} else {
+			br := func(w io.Writer) io.WriteCloser { return brotli.NewWriterLevel(w, cfg.BrotliLevel) }
+			if err := compressFile(p, ".br", br); err != nil {
+				return fmt.Errorf("%s: brotli: %w", p, err)
+			}
+		}
+		count++
+		return nil
+	})
+	return count, err
+}
+
+// compressFile writes file compressed by the writer of newWriter to file+ext
+func compressFile(file, ext string, newWriter func(io.Writer) io.WriteCloser) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	out, err := os.Create(file + ext)
+	if err != nil {
+		return err
+	}
+	zw := newWriter(out)
+	if _, err := zw.Write(data); err != nil {
+		out.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
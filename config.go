@@ -0,0 +1,484 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// DefaultConfigFile is read at startup when present
+const DefaultConfigFile = "blog.yaml"
+
+// Config holds the site settings. Every field has a default, so blog.yaml
+// only needs the keys that differ.
+type Config struct {
+	InputDir  string `yaml:"input_dir"`
+	OutputDir string `yaml:"output_dir"`
+	BaseURL   string `yaml:"base_url"`
+
+	// Site title and description used by the shell
+	Title       string `yaml:"title"`
+	Description string `yaml:"description"`
+
+	// Chroma style of fenced code blocks
+	HighlightStyle string `yaml:"highlight_style"`
+
+	Theme ThemeConfig `yaml:"theme"`
+
+	// Output profile: spa (the Vue app shell) or html (plain pages with the
+	// menu rendered in, no JavaScript; implies path routing)
+	Profile string `yaml:"profile"`
+
+	// URL style: hash or path routing, and for page URLs a trailing slash
+	// (/guide/) or explicit index file (/guide/index.html)
+	URLRouting       string `yaml:"url_routing"`
+	URLTrailingSlash bool   `yaml:"url_trailing_slash"`
+	URLIndexFile     bool   `yaml:"url_index_file"`
+
+	// Hosts to write rewrite rules for with path routing, so deep links
+	// load the app: any of netlify, vercel and nginx
+	HostRewrites []string `yaml:"host_rewrites"`
+
+	// Maximum length (in characters) of descriptions taken from the first paragraph
+	DescriptionLength int `yaml:"description_length"`
+
+	// Keep inline code and emphasis markup in TOC entries (TOCEntry.HTML)
+	TOCMarkup bool `yaml:"toc_markup"`
+
+	// Language used to derive titles from file names
+	TitleLocale string `yaml:"title_locale"`
+
+	// Attributes added to links that leave the site (empty disables each one)
+	ExternalLinkTarget string `yaml:"external_link_target"`
+	ExternalLinkRel    string `yaml:"external_link_rel"`
+	ExternalLinkClass  string `yaml:"external_link_class"`
+
+	// Glossary terms get an <abbr> tooltip, only on their first use per page
+	GlossaryFile      string `yaml:"glossary_file"`
+	GlossaryFirstOnly bool   `yaml:"glossary_first_only"`
+
+	// Seconds a page may take to render before it is skipped (0 for no limit)
+	RenderTimeout int `yaml:"render_timeout"`
+
+	// Maximum nesting of {{include:...}} directives
+	IncludeMaxDepth int `yaml:"include_max_depth"`
+
+	// Markdown files larger than warn_file_kb are reported; files larger
+	// than max_file_kb are skipped without being read (0 for no limit)
+	WarnFileKB int `yaml:"warn_file_kb"`
+	MaxFileKB  int `yaml:"max_file_kb"`
+
+	// URLs per sitemap file before splitting into a sitemap index (max 50000)
+	SitemapMaxURLs int `yaml:"sitemap_max_urls"`
+
+	// Sitemap changefreq by slug prefix, e.g. {"/news": daily}; pages not
+	// matched get a value guessed from their publish and update dates
+	SitemapChangefreq map[string]string `yaml:"sitemap_changefreq"`
+
+	// Google News sitemap of posts published in the last 48 hours
+	NewsSitemap         bool   `yaml:"news_sitemap"`
+	NewsPublicationName string `yaml:"news_publication_name"`
+	NewsLanguage        string `yaml:"news_language"`
+
+	// Number of similar pages listed as related, and the minimum similarity (0-1)
+	RelatedCount    int     `yaml:"related_count"`
+	RelatedMinScore float64 `yaml:"related_min_score"`
+
+	// External summarizer used for pages without a description: a command
+	// reading the page text on stdin, or an HTTP endpoint. Empty disables it.
+	SummarizeCommand  string `yaml:"summarize_command"`
+	SummarizeEndpoint string `yaml:"summarize_endpoint"`
+	SummaryCacheFile  string `yaml:"summary_cache_file"`
+
+	// Rendered pages of the previous build, reused for unchanged files (empty disables)
+	BuildCacheFile string `yaml:"build_cache_file"`
+
+	// Folder copied as is into the output folder (favicons, downloads, ...);
+	// other non-markdown files in the content folder are copied too
+	StaticDir string `yaml:"static_dir"`
+
+	// Responsive images: widths (px) of the resized copies made of JPEG and
+	// PNG files shown by pages (empty disables), their quality (1-100), the
+	// <img sizes> hint, and commands making WebP/AVIF versions, run with
+	// {in}, {out} and {quality} replaced, e.g. "cwebp -q {quality} {in} -o {out}"
+	// (see README.md for the tools)
+	ImageWidths      []int  `yaml:"image_widths"`
+	ImageQuality     int    `yaml:"image_quality"`
+	ImageSizes       string `yaml:"image_sizes"`
+	ImageWebPCommand string `yaml:"image_webp_command"`
+	ImageAVIFCommand string `yaml:"image_avif_command"`
+
+	// QR code PNG of every page pointing at its canonical URL, written to
+	// qr/<slug>.png, size px wide. An optional command run with {url} and
+	// {out} replaced, e.g. "qrencode -s 8 -m 2 -o {out} {url}", replaces the
+	// built-in encoder and also turns the codes on.
+	QRCodes   bool   `yaml:"qr_codes"`
+	QRSize    int    `yaml:"qr_size"`
+	QRCommand string `yaml:"qr_command"`
+
+	// Keep the output readable: indented db.json and HTML as rendered,
+	// instead of compact JSON and HTML with whitespace collapsed
+	Pretty bool `yaml:"pretty"`
+
+	// Add a content hash to the names of db.json, search.json, palette.json
+	// and copied content assets (db.3f9a1c2e.json), listed in
+	// asset-manifest.json, so hosts can cache them forever
+	Fingerprint bool `yaml:"fingerprint"`
+
+	// Also write .gz and .br copies of the HTML, JSON and XML output, for
+	// hosts serving precompressed files. Levels: gzip 1-9, brotli 0-11. An
+	// optional brotli command replaces the built-in encoder; it is run with
+	// {in}, {out} and {level} replaced, e.g. "brotli -q {level} -o {out} {in}".
+	Precompress   bool   `yaml:"precompress"`
+	GzipLevel     int    `yaml:"gzip_level"`
+	BrotliLevel   int    `yaml:"brotli_level"`
+	BrotliCommand string `yaml:"brotli_command"`
+
+	// HTML partials injected into the shell (before-head-end.html, ...)
+	PartialsDir string `yaml:"partials_dir"`
+
+	// Templates replacing the built-in ones of the app shell by file name
+	// (shell.html, sidebar.html, app.html, ...), with [[ ]] as delimiters,
+	// and markdown shortcodes in shortcodes/<name>.html
+	TemplatesDir string `yaml:"templates_dir"`
+
+	// Include pages marked `draft: true`, and pages published in the
+	// future, for preview builds
+	Drafts bool `yaml:"drafts"`
+	Future bool `yaml:"future"`
+
+	// Build the site as it will be at this moment (a frontmatter date, e.g.
+	// 2026-03-01 09:00), instead of now: pages published up to then are
+	// included, so a release can be built ahead of launch
+	ReleaseAt string `yaml:"release_at"`
+
+	// Also publish the stats.json numbers as a /stats page
+	StatsPage bool `yaml:"stats_page"`
+
+	// Also write catalog.json and publish a filterable /all-guides page
+	// over it (facets: type, tags, platform, updated)
+	CatalogPage bool `yaml:"catalog_page"`
+
+	// Also publish a /whats-new page listing pages added or substantially
+	// updated within this many days or builds (0 disables each), and the
+	// file remembering the changes of previous builds
+	WhatsNewDays      int    `yaml:"whats_new_days"`
+	WhatsNewBuilds    int    `yaml:"whats_new_builds"`
+	ChangeHistoryFile string `yaml:"change_history_file"`
+
+	// CODEOWNERS file assigning owners to pages without `owner` frontmatter
+	// (empty looks in the usual places: CODEOWNERS, .github/CODEOWNERS, ...)
+	CodeOwnersFile string `yaml:"codeowners_file"`
+
+	// Approved content of pages with `locked: true` frontmatter; commit it
+	// with the content. The build fails when a locked page changes without a
+	// new `locked_approved_by`, or is removed without an entry in the
+	// `removals` of the file (empty disables locking).
+	LockFile string `yaml:"lock_file"`
+
+	// Pages not updated for this many months are listed in stale.json
+	// (0 disables), with overrides per top-level section (0 exempts it)
+	StaleMonths   int            `yaml:"stale_months"`
+	StaleSections map[string]int `yaml:"stale_sections"`
+
+	// Pages with `review_due` frontmatter within this many days are listed
+	// as due soon in review.json; with review_badge, pages past the date
+	// show a notice on top
+	ReviewSoonDays int  `yaml:"review_soon_days"`
+	ReviewBadge    bool `yaml:"review_badge"`
+
+	// Optional file defining the whole menu instead of the folder tree
+	NavFile string `yaml:"nav_file"`
+
+	// Links, separators, pages and groups added to the menu, written like
+	// nav.yaml entries, with `parent` and `weight` to place them
+	Menu []NavEntry `yaml:"menu"`
+
+	// Feeds of dated pages: any of rss, atom and json (empty disables feeds),
+	// and the number of entries per feed
+	FeedFormats []string `yaml:"feed_formats"`
+	FeedLimit   int      `yaml:"feed_limit"`
+	FeedContent string   `yaml:"feed_content"` // summary or full
+
+	// Extra feeds per top-level section (vps/rss.xml) and per category
+	// (category/releases/rss.xml)
+	SectionFeeds  bool `yaml:"section_feeds"`
+	CategoryFeeds bool `yaml:"category_feeds"`
+
+	// Taxonomies besides tags and category, each read from the frontmatter
+	// key of its name (a list or comma separated string) and listed on term
+	// pages, e.g. {platform: {title: Platforms, label: Platform}}
+	Taxonomies map[string]TaxonomyConfig `yaml:"taxonomies"`
+
+	// Section listed newest first on its index page, e.g. "posts" (empty
+	// disables the listing), and the number of posts per listing page
+	PostsSection string `yaml:"posts_section"`
+	PostsPerPage int    `yaml:"posts_per_page"`
+
+	// Layout of db.json, for custom frontends. Within a version fields are
+	// only ever added; anything that would break readers gets a new version.
+	//   1: every page with its content (default)
+	//   2: page content moved to pages/<slug>.json, loaded per page
+	APIVersion int `yaml:"api_version"`
+
+	// Link to edit the source of a page, with {path} replaced by its path in
+	// the content folder, e.g. https://github.com/me/docs/edit/main/content/{path}
+	EditURL string `yaml:"edit_url"`
+
+	// Author named in structured data of pages without `author` frontmatter
+	// (empty credits the site)
+	Author string `yaml:"author"`
+
+	// Social previews: image used for pages without `image` frontmatter, and
+	// the site's Twitter/X handle (@name)
+	SocialImage string `yaml:"social_image"`
+	TwitterSite string `yaml:"twitter_site"`
+
+	// Check the written db.json against db.schema.json, failing the build
+	// when it does not match
+	ValidateOutput bool `yaml:"validate_output"`
+
+	// Fail the build on broken links, such as wiki links to missing pages,
+	// instead of only reporting them
+	Strict bool `yaml:"strict"`
+
+	// Also write every page as static HTML (<slug>/index.html) for crawlers
+	// and readers without JavaScript
+	Prerender bool `yaml:"prerender"`
+
+	// Also write a lightweight variant of every page (lite/<slug>/) for
+	// slow connections, linked from the full page as an alternate
+	LitePages bool `yaml:"lite_pages"`
+
+	// Moved paths, e.g. {"/v1/setup": "/v2/install"}, merged with the
+	// `aliases` and `previously` frontmatter of pages, and the formats the
+	// redirect map is exported in: any of json, netlify, nginx and caddy
+	Redirects       map[string]string `yaml:"redirects"`
+	RedirectFormats []string          `yaml:"redirect_formats"`
+}
+
+// TaxonomyConfig describes a custom taxonomy
+type TaxonomyConfig struct {
+	Title string `yaml:"title"` // of the overview page, e.g. "Platforms" (default: the name)
+	Label string `yaml:"label"` // of a single term, e.g. "Platform" (default: the title)
+	Slug  string `yaml:"slug"`  // route of the overview page (default: /<name>)
+}
+
+// ThemeConfig holds the look of the shell. Colors, fonts and sizes become
+// CSS custom properties (--color-primary, --sidebar-width, ...) on :root.
+type ThemeConfig struct {
+	DarkMode string `yaml:"dark_mode"` // light, dark or auto (follow the OS), until the reader toggles it
+
+	PrimaryColor     string `yaml:"primary_color"`      // links, active items and focus rings
+	PrimaryColorDark string `yaml:"primary_color_dark"` // the same in dark mode
+	FontFamily       string `yaml:"font_family"`
+	FontURL          string `yaml:"font_url"` // stylesheet loading the font (empty for system fonts)
+	MonoFont         string `yaml:"font_mono"`
+	Radius           string `yaml:"radius"` // corners of buttons, inputs and menu items
+	SidebarWidth     string `yaml:"sidebar_width"`
+	ContentWidth     string `yaml:"content_width"` // maximum width of page text
+
+	// Further custom properties for partials, e.g. {"header-height": "4rem"}
+	Variables map[string]string `yaml:"variables"`
+}
+
+// Dark mode settings of the shell
+const (
+	DarkModeLight = "light"
+	DarkModeDark  = "dark"
+	DarkModeAuto  = "auto"
+)
+
+// Output profiles
+const (
+	ProfileSPA  = "spa"
+	ProfileHTML = "html"
+)
+
+// cfg is the active configuration
+var cfg = defaultConfig()
+
+func defaultConfig() Config {
+	return Config{
+		InputDir:  "./content",
+		OutputDir: "./public",
+		BaseURL:   "https://mysite.com",
+
+		Title:       "Docs",
+		Description: "Documentation",
+
+		HighlightStyle: "dracula",
+		Theme: ThemeConfig{
+			DarkMode:         DarkModeLight,
+			PrimaryColor:     "#2563eb",
+			PrimaryColorDark: "#60a5fa",
+			FontFamily:       "Inter, sans-serif",
+			FontURL:          "https://fonts.googleapis.com/css2?family=Inter:wght@400;500;600;700&display=swap",
+			MonoFont:         "ui-monospace, SFMono-Regular, Menlo, Consolas, monospace",
+			Radius:           "0.375rem",
+			SidebarWidth:     "16rem",
+			ContentWidth:     "48rem",
+		},
+		Profile: ProfileSPA,
+
+		URLRouting:   RoutingHash,
+		HostRewrites: []string{RewritesNetlify, RewritesVercel, RewritesNginx},
+
+		DescriptionLength: 160,
+		TOCMarkup:         true,
+		TitleLocale:       "en",
+
+		ExternalLinkTarget: "_blank",
+		ExternalLinkRel:    "noopener noreferrer",
+		ExternalLinkClass:  "external-link",
+
+		GlossaryFile:      "./glossary.yaml",
+		GlossaryFirstOnly: true,
+
+		IncludeMaxDepth: 5,
+		RenderTimeout:   30,
+		WarnFileKB:      1024,
+		MaxFileKB:       10240,
+		SitemapMaxURLs:  50000,
+
+		NewsPublicationName: "Docs",
+		NewsLanguage:        "en",
+
+		RelatedCount:    5,
+		RelatedMinScore: 0.1,
+
+		SummaryCacheFile:  "./.blogcache/summaries.json",
+		BuildCacheFile:    "./.blogcache/manifest.json",
+		ChangeHistoryFile: "./.blogcache/changes.json",
+		LockFile:          "./content.lock.json",
+		ReviewSoonDays:    30,
+		PartialsDir:       "./partials",
+		TemplatesDir:      "./templates",
+		StaticDir:         "./static",
+		ImageQuality:      80,
+		QRSize:            256,
+		GzipLevel:         9,
+		BrotliLevel:       11,
+		NavFile:           "./nav.yaml",
+
+		FeedLimit:   20,
+		FeedContent: FeedContentSummary,
+
+		PostsPerPage: 10,
+		APIVersion:   1,
+	}
+}
+
+// LoadConfig reads a config file over the defaults. A missing file is only
+// an error when required is set.
+func LoadConfig(path string, required bool) (Config, error) {
+	c := defaultConfig()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) && !required {
+		return c, nil
+	}
+	if err != nil {
+		return c, err
+	}
+	if err := yaml.UnmarshalStrict(data, &c); err != nil {
+		return c, fmt.Errorf("invalid %s: %w", path, err)
+	}
+	if c.Profile == ProfileHTML {
+		c.URLRouting = RoutingPath // plain pages live at their own URLs
+	}
+	if err := c.validate(); err != nil {
+		return c, fmt.Errorf("invalid %s: %w", path, err)
+	}
+	return c, nil
+}
+
+func (c Config) validate() error {
+	if c.URLRouting != RoutingHash && c.URLRouting != RoutingPath {
+		return fmt.Errorf("url_routing must be %q or %q, got %q", RoutingHash, RoutingPath, c.URLRouting)
+	}
+	if c.Profile != ProfileSPA && c.Profile != ProfileHTML {
+		return fmt.Errorf("profile must be %q or %q, got %q", ProfileSPA, ProfileHTML, c.Profile)
+	}
+	switch c.Theme.DarkMode {
+	case DarkModeLight, DarkModeDark, DarkModeAuto:
+	default:
+		return fmt.Errorf("theme.dark_mode must be %q, %q or %q, got %q", DarkModeLight, DarkModeDark, DarkModeAuto, c.Theme.DarkMode)
+	}
+	if err := c.Theme.validate(); err != nil {
+		return err
+	}
+	for _, format := range c.FeedFormats {
+		if _, ok := feedFiles[format]; !ok {
+			return fmt.Errorf("unknown feed format %q, use %q, %q or %q", format, FeedRSS, FeedAtom, FeedJSON)
+		}
+	}
+	if c.FeedContent != FeedContentSummary && c.FeedContent != FeedContentFull {
+		return fmt.Errorf("feed_content must be %q or %q, got %q", FeedContentSummary, FeedContentFull, c.FeedContent)
+	}
+	for prefix, freq := range c.SitemapChangefreq {
+		if !validChangefreq[freq] {
+			return fmt.Errorf("sitemap_changefreq: invalid value %q for %s", freq, prefix)
+		}
+	}
+	if _, ok := parseDate(c.ReleaseAt); c.ReleaseAt != "" && !ok {
+		return fmt.Errorf("release_at: unrecognized date %q", c.ReleaseAt)
+	}
+	if c.WarnFileKB < 0 || c.MaxFileKB < 0 {
+		return fmt.Errorf("warn_file_kb and max_file_kb must not be negative")
+	}
+	if c.RenderTimeout < 0 {
+		return fmt.Errorf("render_timeout must not be negative, got %d", c.RenderTimeout)
+	}
+	if c.SitemapMaxURLs <= 0 || c.SitemapMaxURLs > 50000 {
+		return fmt.Errorf("sitemap_max_urls must be between 1 and 50000, got %d", c.SitemapMaxURLs)
+	}
+	for from, to := range c.Redirects {
+		if !strings.HasPrefix(from, "/") || !strings.HasPrefix(to, "/") {
+			return fmt.Errorf("redirects: %q -> %q must both be site paths starting with /", from, to)
+		}
+	}
+	for _, format := range c.RedirectFormats {
+		if _, ok := redirectFiles[format]; !ok {
+			return fmt.Errorf("unknown redirect format %q, use %q, %q, %q or %q", format, RedirectsJSON, RedirectsNetlify, RedirectsNginx, RedirectsCaddy)
+		}
+	}
+	for _, host := range c.HostRewrites {
+		if host != RewritesNetlify && host != RewritesVercel && host != RewritesNginx {
+			return fmt.Errorf("unknown host_rewrites entry %q, use %q, %q or %q", host, RewritesNetlify, RewritesVercel, RewritesNginx)
+		}
+	}
+	if c.APIVersion != 1 && c.APIVersion != 2 {
+		return fmt.Errorf("api_version must be 1 or 2, got %d", c.APIVersion)
+	}
+	for _, width := range c.ImageWidths {
+		if width <= 0 {
+			return fmt.Errorf("image_widths must be positive, got %d", width)
+		}
+	}
+	if c.ImageQuality < 1 || c.ImageQuality > 100 {
+		return fmt.Errorf("image_quality must be between 1 and 100, got %d", c.ImageQuality)
+	}
+	if c.GzipLevel < 1 || c.GzipLevel > 9 {
+		return fmt.Errorf("gzip_level must be between 1 and 9, got %d", c.GzipLevel)
+	}
+	if c.QRSize <= 0 {
+		return fmt.Errorf("qr_size must be positive, got %d", c.QRSize)
+	}
+	if c.BrotliLevel < 0 || c.BrotliLevel > 11 {
+		return fmt.Errorf("brotli_level must be between 0 and 11, got %d", c.BrotliLevel)
+	}
+	for name, t := range c.Taxonomies {
+		if termSlug(name) != name || frontmatterKeys[name] || name == TaxonomyCategories {
+			return fmt.Errorf("taxonomies: invalid name %q, use a lowercase word that is not a built-in frontmatter key", name)
+		}
+		if t.Slug != "" && (!strings.HasPrefix(t.Slug, "/") || normalizeSlug(t.Slug) != t.Slug) {
+			return fmt.Errorf("taxonomies: slug %q of %s must be a lowercase site path starting with /", t.Slug, name)
+		}
+	}
+	if c.PostsPerPage <= 0 {
+		return fmt.Errorf("posts_per_page must be positive, got %d", c.PostsPerPage)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+
+	"gopkg.in/yaml.v2"
+)
+
+// contentTypes maps a content type to the struct its frontmatter decodes into
+var contentTypes = make(map[string]reflect.Type)
+
+// Validator is implemented by content types that check their own fields
+type Validator interface {
+	Validate() error
+}
+
+// RegisterContentType makes pages of the given type (frontmatter `type:`,
+// or else their top-level folder) decode their frontmatter into a new
+// value of proto's struct, using its yaml tags:
+//
+//	type Recipe struct {
+//		Servings int      `yaml:"servings"`
+//		Steps    []string `yaml:"steps"`
+//	}
+//	RegisterContentType("recipes", Recipe{})
+//
+// The decoded value is published as the page's Data.
+func RegisterContentType(name string, proto interface{}) {
+	t := reflect.TypeOf(proto)
+	if t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("content type %q: %T is not a struct", name, proto))
+	}
+	contentTypes[name] = t
+}
+
+// DecodeFrontmatter decodes meta into the struct registered for typ. It
+// returns nil without an error when no struct is registered.
+func DecodeFrontmatter(typ string, meta map[string]interface{}) (interface{}, error) {
+	t, ok := contentTypes[typ]
+	if !ok {
+		return nil, nil
+	}
+	data, err := yaml.Marshal(meta)
+	if err != nil {
+		return nil, err
+	}
+	v := reflect.New(t)
+	if err := yaml.Unmarshal(data, v.Interface()); err != nil {
+		return nil, fmt.Errorf("invalid %s frontmatter: %w", typ, err)
+	}
+	if val, ok := v.Interface().(Validator); ok {
+		if err := val.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid %s frontmatter: %w", typ, err)
+		}
+	}
+	return v.Interface(), nil
+}
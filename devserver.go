@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/gorilla/websocket"
+)
+
+// liveReloadPath is the WebSocket endpoint the served shell listens on
+const liveReloadPath = "/__livereload"
+
+// rebuildDelay collects the burst of events an editor save produces
+const rebuildDelay = 150 * time.Millisecond
+
+// liveReloadScript reloads the page when the server reports a rebuild, and
+// after the server comes back from a restart.
+const liveReloadScript = `<script>
+(function () {
+    var url = (location.protocol === 'https:' ? 'wss://' : 'ws://') + location.host + '__BASE__` + liveReloadPath + `';
+    var connect = function (reconnecting) {
+        var ws = new WebSocket(url);
+        ws.onopen = function () { if (reconnecting) location.reload(); };
+        ws.onmessage = function () { location.reload(); };
+        ws.onclose = function () { setTimeout(function () { connect(true); }, 1000); };
+    };
+    connect(false);
+})();
+</script>`
+
+// reloader keeps the connected browsers and tells them to reload
+type reloader struct {
+	upgrader websocket.Upgrader
+	mu       sync.Mutex
+	clients  map[*websocket.Conn]bool
+}
+
+func newReloader() *reloader {
+	return &reloader{clients: make(map[*websocket.Conn]bool)}
+}
+
+func (rl *reloader) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := rl.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	rl.mu.Lock()
+	rl.clients[conn] = true
+	rl.mu.Unlock()
+
+	// Block until the browser goes away
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			break
+		}
+	}
+	rl.mu.Lock()
+	delete(rl.clients, conn)
+	rl.mu.Unlock()
+	conn.Close()
+}
+
+// Reload notifies every connected browser
+func (rl *reloader) Reload() {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	for conn := range rl.clients {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte("reload")); err != nil {
+			conn.Close()
+			delete(rl.clients, conn)
+		}
+	}
+}
+
+// withLiveReload serves the app shell with the live reload script added
+// and everything else from next.
+func withLiveReload(next http.Handler, rl *reloader) http.Handler {
+	script := strings.Replace(liveReloadScript, "__BASE__", basePath(), 1)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == basePath()+liveReloadPath {
+			rl.ServeHTTP(w, r)
+			return
+		}
+		rec := &shellRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		if rec.shell == nil {
+			return
+		}
+		body := bytes.Replace(rec.shell.Bytes(), []byte("</body>"), []byte(script+"\n</body>"), 1)
+		w.Header().Del("Content-Length")
+		w.WriteHeader(rec.status)
+		w.Write(body)
+	})
+}
+
+// shellRecorder buffers HTML responses so the script can be injected;
+// other responses pass straight through.
+type shellRecorder struct {
+	http.ResponseWriter
+	status int
+	shell  *bytes.Buffer
+}
+
+func (rec *shellRecorder) WriteHeader(status int) {
+	rec.status = status
+	if status == http.StatusOK && strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") {
+		rec.shell = new(bytes.Buffer)
+		return
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *shellRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.WriteHeader(http.StatusOK)
+	}
+	if rec.shell != nil {
+		return rec.shell.Write(b)
+	}
+	return rec.ResponseWriter.Write(b)
+}
+
+// watchedPages holds a hash of the content of every page of the last build
+// in watch mode, to tell pages that were renamed from those deleted; it is
+// nil when not watching. watchRedirects sends the old paths of the pages
+// renamed since the server started to their new path.
+var (
+	watchedPages   map[string]string
+	watchRedirects = make(map[string]string)
+)
+
+// trackRenames compares the pages of a rebuild with the previous one in
+// watch mode. A page that is gone and a new page with the same content are
+// taken as a rename, and its old path redirects to the new one for the rest
+// of the session, so open browsers follow it on reload.
+func trackRenames(pages map[string]PageData) {
+	if watchedPages == nil {
+		return
+	}
+	current := make(map[string]string, len(pages))
+	added := make(map[string]string) // content hash -> new slug
+	for slug, page := range pages {
+		current[slug] = hashBytes([]byte(page.Content))
+		if _, ok := watchedPages[slug]; !ok && page.Content != "" {
+			added[current[slug]] = slug
+		}
+	}
+	first := len(watchedPages) == 0
+	for _, slug := range sortedKeys(watchedPages) {
+		if _, ok := current[slug]; ok || first {
+			continue
+		}
+		to, renamed := added[watchedPages[slug]]
+		for from, target := range watchRedirects {
+			if target == slug {
+				if renamed {
+					watchRedirects[from] = to
+				} else {
+					delete(watchRedirects, from)
+				}
+			}
+		}
+		if renamed {
+			fmt.Printf("Renamed %s -> %s\n", slug, to)
+			watchRedirects[slug] = to
+		} else {
+			fmt.Println("Removed", slug)
+		}
+	}
+	for from := range watchRedirects {
+		if _, ok := current[from]; ok {
+			delete(watchRedirects, from) // a page is back at its old path
+		}
+	}
+	watchedPages = current
+}
+
+// watchSite rebuilds the site whenever content, static files, the glossary,
+// nav file, partials or templates change, calling onBuild after each successful rebuild.
+func watchSite(onBuild func()) (*fsnotify.Watcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range []string{cfg.InputDir, cfg.PartialsDir, cfg.TemplatesDir, cfg.StaticDir} {
+		if err := watchTree(watcher, dir); err != nil {
+			watcher.Close()
+			return nil, err
+		}
+	}
+	// Single files are watched through their folder, other files there are ignored
+	files := make(map[string]bool)
+	for _, file := range []string{cfg.GlossaryFile, cfg.NavFile} {
+		files[filepath.Clean(file)] = true
+		watcher.Add(filepath.Dir(file))
+	}
+	relevant := func(path string) bool {
+		path = filepath.Clean(path)
+		return files[path] || within(path, cfg.InputDir) || within(path, cfg.PartialsDir) || within(path, cfg.TemplatesDir) || within(path, cfg.StaticDir)
+	}
+
+	go func() {
+		var timer *time.Timer
+		rebuild := make(chan struct{}, 1)
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if event.Has(fsnotify.Chmod) || !relevant(event.Name) {
+					continue
+				}
+				if event.Has(fsnotify.Create) {
+					if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+						watchTree(watcher, event.Name)
+					}
+				}
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = time.AfterFunc(rebuildDelay, func() {
+					select {
+					case rebuild <- struct{}{}:
+					default:
+					}
+				})
+			case <-rebuild:
+				if err := build(); err != nil {
+					fmt.Println("Error:", err)
+					continue
+				}
+				onBuild()
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				fmt.Println("Error watching files:", err)
+			}
+		}
+	}()
+	return watcher, nil
+}
+
+// watchTree adds dir and its subfolders to the watcher; a missing dir is skipped
+func watchTree(watcher *fsnotify.Watcher, dir string) error {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil
+	}
+	return filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return watcher.Add(path)
+		}
+		return nil
+	})
+}
+
+// within reports whether path is dir or inside it
+func within(path, dir string) bool {
+	rel, err := filepath.Rel(filepath.Clean(dir), path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+)
+
+// DirMetaFile is the optional per-folder settings file
+const DirMetaFile = "_meta.yaml"
+
+// DirMeta holds the settings of a content folder read from its _meta.yaml
+type DirMeta struct {
+	Title    string                 `yaml:"title"`    // menu label of the folder itself
+	Defaults map[string]interface{} `yaml:"defaults"` // frontmatter defaults for pages in the folder
+	Labels   map[string]string      `yaml:"labels"`   // menu labels keyed by child file/folder name
+	Order    []string               `yaml:"order"`    // explicit child ordering, unlisted children follow
+	Expand   string                 `yaml:"expand"`   // initial sidebar state of the folder
+}
+
+// LoadDirMeta reads the _meta.yaml of a folder, returning nil if there is none
+func LoadDirMeta(dir string) (*DirMeta, error) {
+	data, err := os.ReadFile(filepath.Join(dir, DirMetaFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var m DirMeta
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid %s in %s: %w", DirMetaFile, dir, err)
+	}
+	if !validExpand(m.Expand) {
+		return nil, fmt.Errorf("invalid %s in %s: unknown expand state %q", DirMetaFile, dir, m.Expand)
+	}
+	return &m, nil
+}
+
+// ApplyDefaults fills frontmatter keys missing from meta
+func (m *DirMeta) ApplyDefaults(meta map[string]interface{}) {
+	for k, v := range m.Defaults {
+		if _, ok := meta[k]; !ok {
+			meta[k] = v
+		}
+	}
+}
+
+// LoadCascade reads the `cascade` frontmatter of the _index.md of a folder:
+// defaults for every page below the folder, returning nil if there are none
+//
+//	cascade:
+//	  category: Tutorials
+//	  author: Jane
+func LoadCascade(dir string) (map[string]interface{}, error) {
+	file := filepath.Join(dir, SectionIndex+".md")
+	data, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var front struct {
+		Cascade map[string]interface{} `yaml:"cascade"`
+	}
+	if err := yaml.Unmarshal(frontmatterBlock(data), &front); err != nil {
+		return nil, fmt.Errorf("invalid frontmatter in %s: %w", file, err)
+	}
+	return front.Cascade, nil
+}
+
+// applyCascades fills the frontmatter keys missing from meta of a page in
+// dir with the cascade of its folder and then of each folder above it, so
+// the closest one wins. The _index.md of a folder is not affected by its
+// own cascade.
+func applyCascades(meta map[string]interface{}, dir string, section bool, cascades map[string]map[string]interface{}) {
+	for {
+		if !section {
+			for k, v := range cascades[dir] {
+				if _, ok := meta[k]; !ok {
+					meta[k] = v
+				}
+			}
+		}
+		if dir == "" {
+			return
+		}
+		section = false
+		if dir = path.Dir(dir); dir == "." {
+			dir = ""
+		}
+	}
+}
+
+// applyDirMeta relabels and reorders the menu nodes of dir (and below)
+// according to the loaded _meta.yaml files.
+func applyDirMeta(nodes []*MenuItem, dir string, metas map[string]*DirMeta) {
+	m := metas[dir]
+	for _, node := range nodes {
+		if node.IsFolder {
+			childDir := path.Join(dir, node.name)
+			if cm := metas[childDir]; cm != nil {
+				if cm.Title != "" {
+					node.Title = cm.Title
+				}
+				node.Expand = cm.Expand
+			}
+			applyDirMeta(node.Children, childDir, metas)
+		}
+		if m != nil && m.Labels[node.name] != "" {
+			node.Title = m.Labels[node.name]
+		}
+	}
+	if m == nil || len(m.Order) == 0 {
+		return
+	}
+	rank := make(map[string]int, len(m.Order))
+	for i, name := range m.Order {
+		rank[name] = i + 1
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		if nodes[i].Slug == "/" || nodes[j].Slug == "/" {
+			return nodes[i].Slug == "/" // Home stays first
+		}
+		ri, rj := rank[nodes[i].name], rank[nodes[j].name]
+		if ri == 0 || rj == 0 {
+			return ri != 0 && rj == 0
+		}
+		return ri < rj
+	})
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Feed formats, each written to its own file
+const (
+	FeedRSS  = "rss"  // feed.xml, rss.xml for section and category feeds
+	FeedAtom = "atom" // atom.xml
+	FeedJSON = "json" // feed.json
+)
+
+// Feed content settings
+const (
+	FeedContentSummary = "summary" // description only
+	FeedContentFull    = "full"    // rendered page with absolute URLs
+)
+
+// scopedRSSFile is the name of the RSS file of section and category feeds;
+// the site feed keeps feed.xml for the readers subscribed to it
+const scopedRSSFile = "rss.xml"
+
+// feedFiles maps a format to its file name and MIME type
+var feedFiles = map[string][2]string{
+	FeedRSS:  {"feed.xml", "application/rss+xml"},
+	FeedAtom: {"atom.xml", "application/atom+xml"},
+	FeedJSON: {"feed.json", "application/feed+json"},
+}
+
+// Feed is a list of dated pages published in the configured formats
+type Feed struct {
+	Title string
+	Dir   string // output subfolder, "" for the site feed
+	Link  string // page the feed belongs to
+	Items []FeedItem
+}
+
+// FeedItem is one entry of a feed
+type FeedItem struct {
+	Slug        string
+	Title       string
+	Description string
+	Content     string // full HTML, empty in summary feeds
+	Categories  []string
+	Published   time.Time
+	Updated     time.Time
+}
+
+// feedItems collects the dated pages accepted by include, newest first,
+// capped at cfg.FeedLimit.
+func feedItems(pages map[string]PageData, include func(slug string) bool) []FeedItem {
+	var items []FeedItem
+	for slug, page := range pages {
+		published, ok := parseDate(page.Published)
+		if !ok || !page.Indexed() || !include(slug) {
+			continue
+		}
+		updated, ok := parseDate(page.Updated)
+		if !ok || updated.Before(published) {
+			updated = published
+		}
+		item := FeedItem{Slug: slug, Title: page.Title, Description: page.Description, Published: published, Updated: updated}
+		if cfg.FeedContent == FeedContentFull {
+			item.Content = absolutizeHTML(page.Content, slug)
+		}
+		if page.Category != "" {
+			item.Categories = append(item.Categories, page.Category)
+		}
+		item.Categories = append(item.Categories, page.Tags...)
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].Published.Equal(items[j].Published) {
+			return items[i].Published.After(items[j].Published)
+		}
+		return items[i].Slug < items[j].Slug
+	})
+	if cfg.FeedLimit > 0 && len(items) > cfg.FeedLimit {
+		items = items[:cfg.FeedLimit]
+	}
+	return items
+}
+
+// SiteFeeds returns the feeds to publish: the site feed, plus one per
+// top-level section (vps/rss.xml) and category (category/releases/rss.xml)
+// when enabled.
+func SiteFeeds(pages map[string]PageData) []Feed {
+	if len(cfg.FeedFormats) == 0 {
+		return nil
+	}
+	all := func(string) bool { return true }
+	feeds := []Feed{{Title: cfg.Title, Link: pageURL("/"), Items: feedItems(pages, all)}}
+
+	sections := make(map[string]bool)
+	categories := make(map[string]string) // slug -> name
+	for slug, page := range pages {
+		if _, ok := parseDate(page.Published); !ok {
+			continue
+		}
+		if s := sectionOf(slug); s != "" {
+			sections[s] = true
+		}
+		if page.Category != "" {
+			categories[termSlug(page.Category)] = page.Category
+		}
+	}
+	if cfg.SectionFeeds {
+		for _, section := range sortedKeys(sections) {
+			items := feedItems(pages, func(slug string) bool { return sectionOf(slug) == section })
+			feeds = append(feeds, Feed{Title: cfg.Title + " - Section: " + titleCase(section), Dir: section, Link: pageURL("/" + section), Items: items})
+		}
+	}
+	if cfg.CategoryFeeds {
+		for _, cs := range sortedKeys(categories) {
+			name := categories[cs]
+			items := feedItems(pages, func(slug string) bool { return termSlug(pages[slug].Category) == cs })
+			feeds = append(feeds, Feed{Title: cfg.Title + " - Category: " + name, Dir: categoryFeedDir(name), Link: pageURL(CategoriesSlug + "/" + cs), Items: items})
+		}
+	}
+	return feeds
+}
+
+// categoryFeedDir returns the output subfolder of the feed of a category
+func categoryFeedDir(category string) string {
+	return strings.TrimPrefix(CategoriesSlug, "/") + "/" + termSlug(category)
+}
+
+// headFeeds returns the feeds the page at slug links to: the site feed,
+// then the feeds of its section and its category, or of the section or
+// category it lists. The shell and other pages without a slug only link to
+// the site feed.
+func headFeeds(feeds []Feed, slug string, page PageData) []Feed {
+	var own []Feed
+	for _, f := range feeds {
+		switch {
+		case f.Dir == "":
+		case slug == "":
+			continue
+		case "/"+f.Dir == slug, f.Dir == sectionOf(slug):
+		case page.Category != "" && f.Dir == categoryFeedDir(page.Category):
+		default:
+			continue
+		}
+		own = append(own, f)
+	}
+	return own
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// feedPath returns the site-relative path of a feed file
+func feedPath(f Feed, format string) string {
+	if f.Dir != "" && format == FeedRSS {
+		return f.Dir + "/" + scopedRSSFile
+	}
+	return strings.TrimPrefix(f.Dir+"/"+feedFiles[format][0], "/")
+}
+
+// WriteFeeds writes every feed in every configured format
+func WriteFeeds(feeds []Feed) error {
+	for _, f := range feeds {
+		if err := os.MkdirAll(filepath.Join(cfg.OutputDir, f.Dir), 0755); err != nil {
+			return err
+		}
+		for _, format := range cfg.FeedFormats {
+			var data []byte
+			switch format {
+			case FeedRSS:
+				data = rssFeed(f)
+			case FeedAtom:
+				data = atomFeed(f)
+			case FeedJSON:
+				data = jsonFeed(f)
+			}
+			if err := os.WriteFile(outputFile(feedPath(f, format)), data, 0644); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// feedLinks returns the autodiscovery <link> tags of the feeds
+func feedLinks(feeds []Feed) string {
+	var buf strings.Builder
+	for _, f := range feeds {
+		for _, format := range cfg.FeedFormats {
+			buf.WriteString(fmt.Sprintf("    <link rel=\"alternate\" type=\"%s\" title=\"%s\" href=\"%s\">\n",
+				feedFiles[format][1], html.EscapeString(f.Title), html.EscapeString(siteURL(feedPath(f, format)))))
+		}
+	}
+	return buf.String()
+}
+
+func rssFeed(f Feed) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	buf.WriteString(`<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom" xmlns:content="http://purl.org/rss/1.0/modules/content/">` + "\n")
+	buf.WriteString("  <channel>\n")
+	buf.WriteString(fmt.Sprintf("    <title>%s</title>\n", xmlEscape(f.Title)))
+	buf.WriteString(fmt.Sprintf("    <link>%s</link>\n", xmlEscape(f.Link)))
+	buf.WriteString(fmt.Sprintf("    <description>%s</description>\n", xmlEscape(cfg.Description)))
+	buf.WriteString(fmt.Sprintf("    <atom:link href=\"%s\" rel=\"self\" type=\"application/rss+xml\"/>\n", xmlEscape(siteURL(feedPath(f, FeedRSS)))))
+	if len(f.Items) > 0 {
+		buf.WriteString(fmt.Sprintf("    <lastBuildDate>%s</lastBuildDate>\n", f.Items[0].Updated.Format(time.RFC1123Z)))
+	}
+	for _, item := range f.Items {
+		buf.WriteString("    <item>\n")
+		buf.WriteString(fmt.Sprintf("      <title>%s</title>\n", xmlEscape(item.Title)))
+		buf.WriteString(fmt.Sprintf("      <link>%s</link>\n", xmlEscape(pageURL(item.Slug))))
+		buf.WriteString(fmt.Sprintf("      <guid>%s</guid>\n", xmlEscape(pageURL(item.Slug))))
+		buf.WriteString(fmt.Sprintf("      <pubDate>%s</pubDate>\n", item.Published.Format(time.RFC1123Z)))
+		buf.WriteString(fmt.Sprintf("      <description>%s</description>\n", xmlEscape(item.Description)))
+		if item.Content != "" {
+			buf.WriteString(fmt.Sprintf("      <content:encoded>%s</content:encoded>\n", xmlEscape(item.Content)))
+		}
+		for _, c := range item.Categories {
+			buf.WriteString(fmt.Sprintf("      <category>%s</category>\n", xmlEscape(c)))
+		}
+		buf.WriteString("    </item>\n")
+	}
+	buf.WriteString("  </channel>\n")
+	buf.WriteString(`</rss>`)
+	return buf.Bytes()
+}
+
+func atomFeed(f Feed) []byte {
+	updated := siteTime()
+	if len(f.Items) > 0 {
+		updated = f.Items[0].Updated
+	}
+	var buf bytes.Buffer
+	buf.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	buf.WriteString(`<feed xmlns="http://www.w3.org/2005/Atom">` + "\n")
+	buf.WriteString(fmt.Sprintf("  <title>%s</title>\n", xmlEscape(f.Title)))
+	buf.WriteString(fmt.Sprintf("  <id>%s</id>\n", xmlEscape(siteURL(feedPath(f, FeedAtom)))))
+	buf.WriteString(fmt.Sprintf("  <link href=\"%s\"/>\n", xmlEscape(f.Link)))
+	buf.WriteString(fmt.Sprintf("  <link href=\"%s\" rel=\"self\"/>\n", xmlEscape(siteURL(feedPath(f, FeedAtom)))))
+	buf.WriteString(fmt.Sprintf("  <updated>%s</updated>\n", updated.Format(time.RFC3339)))
+	buf.WriteString(fmt.Sprintf("  <author><name>%s</name></author>\n", xmlEscape(cfg.Title)))
+	for _, item := range f.Items {
+		buf.WriteString("  <entry>\n")
+		buf.WriteString(fmt.Sprintf("    <title>%s</title>\n", xmlEscape(item.Title)))
+		buf.WriteString(fmt.Sprintf("    <link href=\"%s\"/>\n", xmlEscape(pageURL(item.Slug))))
+		buf.WriteString(fmt.Sprintf("    <id>%s</id>\n", xmlEscape(pageURL(item.Slug))))
+		buf.WriteString(fmt.Sprintf("    <published>%s</published>\n", item.Published.Format(time.RFC3339)))
+		buf.WriteString(fmt.Sprintf("    <updated>%s</updated>\n", item.Updated.Format(time.RFC3339)))
+		buf.WriteString(fmt.Sprintf("    <summary>%s</summary>\n", xmlEscape(item.Description)))
+		if item.Content != "" {
+			buf.WriteString(fmt.Sprintf("    <content type=\"html\">%s</content>\n", xmlEscape(item.Content)))
+		}
+		for _, c := range item.Categories {
+			buf.WriteString(fmt.Sprintf("    <category term=\"%s\"/>\n", xmlEscape(c)))
+		}
+		buf.WriteString("  </entry>\n")
+	}
+	buf.WriteString(`</feed>`)
+	return buf.Bytes()
+}
+
+// jsonFeed renders a JSON Feed 1.1 document
+func jsonFeed(f Feed) []byte {
+	type jsonItem struct {
+		ID            string   `json:"id"`
+		URL           string   `json:"url"`
+		Title         string   `json:"title"`
+		Summary       string   `json:"summary,omitempty"`
+		ContentText   string   `json:"content_text,omitempty"`
+		ContentHTML   string   `json:"content_html,omitempty"`
+		DatePublished string   `json:"date_published"`
+		DateModified  string   `json:"date_modified"`
+		Tags          []string `json:"tags,omitempty"`
+	}
+	doc := struct {
+		Version     string     `json:"version"`
+		Title       string     `json:"title"`
+		HomePageURL string     `json:"home_page_url"`
+		FeedURL     string     `json:"feed_url"`
+		Description string     `json:"description,omitempty"`
+		Items       []jsonItem `json:"items"`
+	}{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       f.Title,
+		HomePageURL: f.Link,
+		FeedURL:     siteURL(feedPath(f, FeedJSON)),
+		Description: cfg.Description,
+		Items:       []jsonItem{},
+	}
+	for _, item := range f.Items {
+		ji := jsonItem{
+			ID:            pageURL(item.Slug),
+			URL:           pageURL(item.Slug),
+			Title:         item.Title,
+			Summary:       item.Description,
+			DatePublished: item.Published.Format(time.RFC3339),
+			DateModified:  item.Updated.Format(time.RFC3339),
+			Tags:          item.Categories,
+		}
+		if item.Content != "" {
+			ji.ContentHTML = item.Content
+		} else {
+			ji.ContentText = item.Description
+		}
+		doc.Items = append(doc.Items, ji)
+	}
+	data, _ := json.MarshalIndent(doc, "", "  ")
+	return data
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"html"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// AssetManifestFile maps the names of fingerprinted files to the names
+// they were written under, e.g. {"db.json": "db.3f9a1c2e.json"}
+const AssetManifestFile = "asset-manifest.json"
+
+// AssetManifest records the output files renamed by content hash, so hosts
+// can cache them forever. Without cfg.Fingerprint every method keeps names
+// as they are and the manifest stays empty.
+type AssetManifest map[string]string
+
+// fingerprintName inserts a short hash of data before the extension of the
+// slash-separated name: vps/diagram.png -> vps/diagram.1a2b3c4d.png
+func fingerprintName(name string, data []byte) string {
+	ext := path.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + hashBytes(data)[:8] + ext
+}
+
+// Name returns the name a file was written under
+func (m AssetManifest) Name(name string) string {
+	if hashed, ok := m[name]; ok {
+		return hashed
+	}
+	return name
+}
+
+// WriteFile writes data to name in the output folder, fingerprinted when
+// enabled
+func (m AssetManifest) WriteFile(name string, data []byte) error {
+	if cfg.Fingerprint {
+		m[name] = fingerprintName(name, data)
+	}
+	return os.WriteFile(outputFile(m.Name(name)), data, 0644)
+}
+
+// Rename fingerprints files already in the output folder (rels,
+// slash-separated) and points the links and images of pages to the new
+// names. The files are copied, so links from outside the site to the
+// original names keep working.
+func (m AssetManifest) Rename(rels []string, pages map[string]PageData) error {
+	if !cfg.Fingerprint || len(rels) == 0 {
+		return nil
+	}
+	for _, rel := range rels {
+		data, err := os.ReadFile(outputFile(rel))
+		if err != nil {
+			return err
+		}
+		m[rel] = fingerprintName(rel, data)
+		if err := os.WriteFile(outputFile(m[rel]), data, 0644); err != nil {
+			return err
+		}
+	}
+	for slug, page := range pages {
+		page.Content = m.rewriteURLs(page.Content)
+		page.Image = m.Link(page.Image)
+		for key, value := range page.Meta {
+			if urlMetaKeys[key] {
+				page.Meta[key] = m.Link(value)
+			}
+		}
+		pages[slug] = page
+	}
+	return nil
+}
+
+// Link returns the plain (not HTML-escaped) URL u pointing to the renamed
+// file when it is the site path or absolute URL of one
+func (m AssetManifest) Link(u string) string {
+	rest := ""
+	if i := strings.IndexAny(u, "?#"); i >= 0 {
+		u, rest = u[:i], u[i:]
+	}
+	prefixes := []string{basePath() + "/"}
+	if base := siteBase(); base != nil && base.IsAbs() {
+		prefixes = append(prefixes, base.String())
+	}
+	for _, prefix := range prefixes {
+		if hashed, ok := m[strings.TrimPrefix(u, prefix)]; ok && strings.HasPrefix(u, prefix) {
+			return prefix + hashed + rest
+		}
+	}
+	return u + rest
+}
+
+// rewriteURLs replaces site URLs of renamed files in rendered HTML
+func (m AssetManifest) rewriteURLs(content string) string {
+	rewrite := func(ref string) string {
+		u := html.UnescapeString(ref)
+		if renamed := m.Link(u); renamed != u {
+			return html.EscapeString(renamed)
+		}
+		return ref
+	}
+	return urlAttrRegex.ReplaceAllStringFunc(content, func(attr string) string {
+		parts := urlAttrRegex.FindStringSubmatch(attr)
+		if !strings.HasSuffix(parts[1], `srcset="`) {
+			return parts[1] + rewrite(parts[2]) + parts[3]
+		}
+		candidates := strings.Split(parts[2], ",")
+		for i, c := range candidates {
+			fields := strings.Fields(c)
+			if len(fields) > 0 {
+				fields[0] = rewrite(fields[0])
+				candidates[i] = strings.Join(fields, " ")
+			}
+		}
+		return parts[1] + strings.Join(candidates, ", ") + parts[3]
+	})
+}
+
+// Save writes asset-manifest.json when anything was fingerprinted
+func (m AssetManifest) Save() error {
+	if len(m) == 0 {
+		return nil
+	}
+	data, _ := json.MarshalIndent(m, "", "  ")
+	return os.WriteFile(filepath.Join(cfg.OutputDir, AssetManifestFile), data, 0644)
+}
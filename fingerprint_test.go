@@ -0,0 +1,49 @@
+package main
+
+import "testing"
+
+func TestAssetManifestLink(t *testing.T) {
+	old := cfg.BaseURL
+	t.Cleanup(func() { cfg.BaseURL = old })
+	cfg.BaseURL = "https://example.com/docs/"
+	m := AssetManifest{"db.json": "db.1a2b3c4d.json", "img/a&b.png": "img/a&b.5e6f7a8b.png"}
+
+	tests := map[string]string{
+		"/docs/db.json":                    "/docs/db.1a2b3c4d.json",
+		"/docs/db.json?v=2#top":            "/docs/db.1a2b3c4d.json?v=2#top",
+		"https://example.com/docs/db.json": "https://example.com/docs/db.1a2b3c4d.json",
+		"/docs/img/a&b.png":                "/docs/img/a&b.5e6f7a8b.png",
+		"/db.json":                         "/db.json",
+		"db.json":                          "db.json",
+		"https://other.com/docs/db.json":   "https://other.com/docs/db.json",
+		"/docs/other.json":                 "/docs/other.json",
+		"":                                 "",
+	}
+	for in, want := range tests {
+		if got := m.Link(in); got != want {
+			t.Errorf("Link(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestAssetManifestRewriteURLs(t *testing.T) {
+	old := cfg.BaseURL
+	t.Cleanup(func() { cfg.BaseURL = old })
+	cfg.BaseURL = "https://example.com/docs/"
+	m := AssetManifest{"a.png": "a.11111111.png", "a-2x.png": "a-2x.22222222.png", "img/a&b.png": "img/a&b.33333333.png"}
+
+	tests := map[string]string{
+		`<img src="/docs/a.png" alt="/docs/a.png">`:        `<img src="/docs/a.11111111.png" alt="/docs/a.png">`,
+		`<a href="https://example.com/docs/a.png#x">a</a>`: `<a href="https://example.com/docs/a.11111111.png#x">a</a>`,
+		`<img src="/docs/img/a&amp;b.png">`:                `<img src="/docs/img/a&amp;b.33333333.png">`,
+		`<img srcset="/docs/a.png 1x,/docs/a-2x.png  2x">`: `<img srcset="/docs/a.11111111.png 1x, /docs/a-2x.22222222.png 2x">`,
+		`<video poster="/docs/a.png" src="/docs/b.mp4">`:   `<video poster="/docs/a.11111111.png" src="/docs/b.mp4">`,
+		`<a href="/docs/other.png">`:                       `<a href="/docs/other.png">`,
+		`<p>/docs/a.png</p>`:                               `<p>/docs/a.png</p>`,
+	}
+	for in, want := range tests {
+		if got := m.rewriteURLs(in); got != want {
+			t.Errorf("rewriteURLs(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+	"gopkg.in/yaml.v2"
+)
+
+// Glossary maps a term (as written in the glossary file) to its definition
+type Glossary map[string]string
+
+// GlossarySlug is the route of the generated glossary page
+const GlossarySlug = "/glossary"
+
+var (
+	siteGlossary Glossary
+	glossaryExpr *regexp.Regexp
+
+	// glossaryUsageKey collects the terms tooltipped while parsing a page
+	glossaryUsageKey = parser.NewContextKey()
+)
+
+// LoadGlossary reads the glossary file and prepares the term matcher.
+// A missing file simply disables glossary tooltips.
+func LoadGlossary(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var g Glossary
+	if err := yaml.Unmarshal(data, &g); err != nil {
+		return fmt.Errorf("invalid glossary %s: %w", path, err)
+	}
+	setGlossary(g)
+	return nil
+}
+
+func setGlossary(g Glossary) {
+	siteGlossary = g
+	glossaryExpr = nil
+	if len(g) == 0 {
+		return
+	}
+	terms := make([]string, 0, len(g))
+	for term := range g {
+		terms = append(terms, term)
+	}
+	// Longest first so "disk quota" wins over "disk"
+	sort.Slice(terms, func(i, j int) bool {
+		if len(terms[i]) != len(terms[j]) {
+			return len(terms[i]) > len(terms[j])
+		}
+		return terms[i] < terms[j]
+	})
+	for i, term := range terms {
+		terms[i] = termPattern(term)
+	}
+	glossaryExpr = regexp.MustCompile(`(?i)` + strings.Join(terms, "|"))
+}
+
+// termPattern matches term as a whole word. Word boundaries are only
+// required next to letters, digits and underscores, which \b can tell
+// apart, so terms like C++, C# and .NET match as well.
+func termPattern(term string) string {
+	pattern := regexp.QuoteMeta(term)
+	if term != "" && isWordByte(term[0]) {
+		pattern = `\b` + pattern
+	}
+	if term != "" && isWordByte(term[len(term)-1]) {
+		pattern += `\b`
+	}
+	return pattern
+}
+
+// isWordByte reports whether c is a word character of \b
+func isWordByte(c byte) bool {
+	return c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// wholeWord reports whether the match at loc is not part of a longer word.
+// \b only knows ASCII, so "café" would otherwise match inside "cafés".
+func wholeWord(value []byte, loc []int) bool {
+	first, _ := utf8.DecodeRune(value[loc[0]:])
+	last, _ := utf8.DecodeLastRune(value[:loc[1]])
+	before, _ := utf8.DecodeLastRune(value[:loc[0]])
+	after, _ := utf8.DecodeRune(value[loc[1]:])
+	return !(isWordRune(first) && isWordRune(before)) && !(isWordRune(last) && isWordRune(after))
+}
+
+// isWordRune reports whether r is a letter, digit or underscore
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// lookup returns the canonical term and definition for a matched word
+func (g Glossary) lookup(word string) (string, string, bool) {
+	if def, ok := g[word]; ok {
+		return word, def, true
+	}
+	for term, def := range g {
+		if strings.EqualFold(term, word) {
+			return term, def, true
+		}
+	}
+	return "", "", false
+}
+
+// glossaryAnchor returns the element ID of a term on the glossary page
+func glossaryAnchor(term string) string {
+	return "term-" + strings.ReplaceAll(strings.ToLower(strings.TrimSpace(term)), " ", "-")
+}
+
+// glossaryLink renders the tooltip markup linking text to its glossary entry
+func glossaryLink(term, def, text string) string {
+	return fmt.Sprintf(`<a href="%s#%s" class="glossary-link"><abbr title="%s" class="glossary-term">%s</abbr></a>`,
+		pageHref(GlossarySlug), glossaryAnchor(term), html.EscapeString(def), html.EscapeString(text))
+}
+
+// glossaryTerms is a goldmark extension wrapping glossary terms found in
+// body text with <abbr> tooltips.
+type glossaryTerms struct {
+	FirstOnly bool
+}
+
+func (e *glossaryTerms) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithASTTransformers(util.Prioritized(e, 600)))
+}
+
+func (e *glossaryTerms) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	if glossaryExpr == nil {
+		return
+	}
+	source := reader.Source()
+
+	// Collect first, the tree is mutated below
+	var nodes []*ast.Text
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n.Kind() {
+		case ast.KindHeading, ast.KindLink, ast.KindAutoLink, ast.KindCodeSpan, ast.KindImage:
+			return ast.WalkSkipChildren, nil
+		}
+		if t, ok := n.(*ast.Text); ok {
+			nodes = append(nodes, t)
+		}
+		return ast.WalkContinue, nil
+	})
+
+	used := make(map[string]bool)
+	for _, t := range nodes {
+		for t != nil {
+			t = e.wrapFirst(t, source, used)
+		}
+	}
+	terms := make([]string, 0, len(used))
+	for term := range used {
+		terms = append(terms, term)
+	}
+	pc.Set(glossaryUsageKey, terms)
+}
+
+// wrapFirst wraps the first eligible term in t and returns the text node
+// holding the remainder, or nil when nothing else can match.
+func (e *glossaryTerms) wrapFirst(t *ast.Text, source []byte, used map[string]bool) *ast.Text {
+	value := t.Segment.Value(source)
+	tags := defTagRegex.FindAllIndex(value, -1)
+	inTag := func(loc []int) bool {
+		for _, tag := range tags {
+			if loc[0] < tag[1] && loc[1] > tag[0] {
+				return true
+			}
+		}
+		return false
+	}
+	for _, loc := range glossaryExpr.FindAllIndex(value, -1) {
+		if inTag(loc) {
+			continue // explicit {{def:term}} tags are rendered later
+		}
+		if !wholeWord(value, loc) {
+			continue
+		}
+		term, def, ok := siteGlossary.lookup(string(value[loc[0]:loc[1]]))
+		if !ok || (e.FirstOnly && used[term]) {
+			continue
+		}
+		used[term] = true
+
+		parent := t.Parent()
+		before := ast.NewTextSegment(t.Segment.WithStop(t.Segment.Start + loc[0]))
+		after := ast.NewTextSegment(t.Segment.WithStart(t.Segment.Start + loc[1]))
+		after.SetSoftLineBreak(t.SoftLineBreak())
+		after.SetHardLineBreak(t.HardLineBreak())
+
+		abbr := ast.NewString([]byte(glossaryLink(term, def, string(value[loc[0]:loc[1]]))))
+		abbr.SetCode(true)
+
+		parent.InsertBefore(parent, t, before)
+		parent.InsertBefore(parent, t, abbr)
+		parent.ReplaceChild(parent, t, after)
+		return after
+	}
+	return nil
+}
+
+// BuildGlossaryPage renders the glossary as a page, listing for each term
+// the pages that use it.
+func BuildGlossaryPage(usage map[string][]string, pages map[string]PageData) PageData {
+	terms := make([]string, 0, len(siteGlossary))
+	for term := range siteGlossary {
+		terms = append(terms, term)
+	}
+	sort.Slice(terms, func(i, j int) bool { return strings.ToLower(terms[i]) < strings.ToLower(terms[j]) })
+
+	var buf strings.Builder
+	var toc []TOCEntry
+	for _, term := range terms {
+		id := glossaryAnchor(term)
+		toc = append(toc, TOCEntry{Title: term, ID: id, Level: 2})
+		buf.WriteString(fmt.Sprintf("<h2 id=\"%s\">%s</h2>\n", id, html.EscapeString(term)))
+		buf.WriteString(fmt.Sprintf("<p>%s</p>\n", html.EscapeString(siteGlossary[term])))
+
+		slugs := usage[term]
+		if len(slugs) == 0 {
+			continue
+		}
+		sort.Strings(slugs)
+		var links []string
+		for _, slug := range slugs {
+			links = append(links, fmt.Sprintf(`<a href="%s">%s</a>`, pageHref(slug), html.EscapeString(pages[slug].Title)))
+		}
+		buf.WriteString(fmt.Sprintf("<p class=\"text-sm\">Used in: %s</p>\n", strings.Join(links, ", ")))
+	}
+
+	return PageData{
+		Title:       "Glossary",
+		Content:     buf.String(),
+		TOC:         toc,
+		Description: "Definitions of the terms used throughout the documentation.",
+	}
+}
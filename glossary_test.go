@@ -0,0 +1,32 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGlossaryExpr(t *testing.T) {
+	old := siteGlossary
+	t.Cleanup(func() { setGlossary(old) })
+	setGlossary(Glossary{"C++": "", "C#": "", ".NET": "", "Node.js": "", "disk": "", "disk quota": "", "café": ""})
+
+	tests := map[string][]string{
+		"Written in C++ and C#.":        {"C++", "C#"},
+		"Runs on .NET and node.js":      {".NET", "node.js"},
+		"the disk quota of a disk":      {"disk quota", "disk"},
+		"disks and diskette":            nil,
+		"a café, a cafés, a Café":       {"café", "Café"},
+		"ABC++ and abc# are not C++ or": {"C++"},
+	}
+	for text, want := range tests {
+		var got []string
+		for _, loc := range glossaryExpr.FindAllStringIndex(text, -1) {
+			if wholeWord([]byte(text), loc) {
+				got = append(got, text[loc[0]:loc[1]])
+			}
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("terms of %q = %q, want %q", text, got, want)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import "encoding/json"
+
+// LinkGraph is graph.json: the content pages and the pages they link to,
+// with the links between them, for a graph view of the site
+type LinkGraph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []PageEdge  `json:"edges"`
+}
+
+// GraphNode is a page of the link graph
+type GraphNode struct {
+	ID       string   `json:"id"` // slug
+	Title    string   `json:"title"`
+	Category string   `json:"category,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	Type     string   `json:"type,omitempty"`
+}
+
+// BuildLinkGraph makes the graph of the links found by CollectLinks. Pages
+// without links are part of it too, generated pages only when linked to.
+func BuildLinkGraph(pages map[string]PageData, edges []PageEdge) LinkGraph {
+	linked := make(map[string]bool)
+	for _, e := range edges {
+		linked[e.To] = true
+	}
+	graph := LinkGraph{Nodes: []GraphNode{}, Edges: edges}
+	if graph.Edges == nil {
+		graph.Edges = []PageEdge{}
+	}
+	for _, slug := range sortedKeys(pages) {
+		page := pages[slug]
+		if page.ModTime.IsZero() && !linked[slug] {
+			continue
+		}
+		graph.Nodes = append(graph.Nodes, GraphNode{ID: slug, Title: page.Title, Category: page.Category, Tags: page.Tags, Type: page.Type})
+	}
+	return graph
+}
+
+// WriteLinkGraph writes graph.json to the output folder
+func WriteLinkGraph(graph LinkGraph, assets AssetManifest) error {
+	data, _ := json.Marshal(graph)
+	return assets.WriteFile("graph.json", data)
+}
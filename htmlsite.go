@@ -0,0 +1,281 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// htmlSiteStyle is the whole stylesheet of the html profile
+const htmlSiteStyle = `
+:root { --bg: #fff; --fg: #1e293b; --muted: #64748b; --link: #2563eb; --border: #e5e7eb; --code: #f3f4f6; }
+__DARK__
+* { box-sizing: border-box; }
+body { margin: 0; background: var(--bg); color: var(--fg); font: 16px/1.6 system-ui, -apple-system, "Segoe UI", sans-serif; }
+a { color: var(--link); }
+.skip { position: absolute; left: -999px; }
+.skip:focus { left: 1rem; top: 1rem; background: var(--bg); padding: 0.5rem; }
+header { display: flex; flex-wrap: wrap; gap: 1rem; align-items: center; justify-content: space-between; padding: 0.75rem 1.5rem; border-bottom: 1px solid var(--border); }
+header .title { font-weight: 600; text-decoration: none; color: var(--fg); }
+.layout { display: flex; flex-wrap: wrap; max-width: 80rem; margin: 0 auto; }
+.site-nav { flex: 0 0 16rem; padding: 1.5rem; border-right: 1px solid var(--border); font-size: 0.9rem; }
+.site-nav ul { list-style: none; margin: 0; padding-left: 0.75rem; }
+.site-nav > ul { padding: 0; }
+.site-nav li { margin: 0.2rem 0; }
+.site-nav summary { cursor: pointer; font-weight: 500; }
+.site-nav [aria-current] { font-weight: 600; }
+main { flex: 1 1 30rem; min-width: 0; padding: 1.5rem 2rem; }
+.meta { color: var(--muted); font-size: 0.9rem; }
+.toc { border: 1px solid var(--border); padding: 0.5rem 1rem; margin: 1rem 0; font-size: 0.9rem; }
+.toc ul { padding-left: 1rem; }
+.breadcrumbs { font-size: 0.9rem; color: var(--muted); }
+pre { padding: 1rem; overflow-x: auto; border-radius: 0.375rem; }
+code { background: var(--code); padding: 0.1rem 0.3rem; border-radius: 0.25rem; }
+pre code { background: none; padding: 0; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid var(--border); padding: 0.3rem 0.6rem; }
+img { max-width: 100%; }
+.layout-default h1:first-of-type, .layout-wide h1:first-of-type { display: none; }
+.admonition { border-left: 4px solid #3b82f6; padding: 0.75rem 1rem; margin: 1rem 0; background: var(--code); }
+.admonition-title { font-weight: 700; }
+.admonition-tip { border-color: #10b981; } .admonition-warning { border-color: #f59e0b; }
+.admonition-important { border-color: #8b5cf6; } .admonition-caution { border-color: #ef4444; }
+.review-badge { display: inline-block; padding: 0.1rem 0.6rem; border-radius: 1rem; font-size: 0.8rem; color: #92400e; background: #fef3c7; }
+.wiki-link-broken { color: #dc2626; text-decoration: underline dotted; }
+.landing-hero { text-align: center; padding: 2rem 0; }
+.landing-hero-image { max-height: 8rem; }
+.landing-hero-title { font-size: 2.5rem; margin: 0.5rem 0; }
+.landing-hero-tagline { font-size: 1.2rem; color: var(--muted); }
+.landing-actions { display: flex; flex-wrap: wrap; justify-content: center; gap: 0.75rem; }
+.landing-button { padding: 0.5rem 1.2rem; border: 1px solid var(--link); border-radius: 9999px; text-decoration: none; font-weight: 600; }
+.landing-button-primary { background: var(--link); color: var(--bg); }
+.landing-features { display: grid; grid-template-columns: repeat(auto-fit, minmax(14rem, 1fr)); gap: 1rem; margin: 1rem 0 2rem; }
+.landing-feature { display: block; padding: 1rem; border: 1px solid var(--border); text-decoration: none; color: var(--fg); }
+.landing-feature p { color: var(--muted); margin: 0; }
+.shortcode-video { position: relative; aspect-ratio: 16 / 9; margin: 1rem 0; }
+.shortcode-video iframe { position: absolute; inset: 0; width: 100%; height: 100%; border: 0; }
+figure { margin: 1rem 0; } figcaption { color: var(--muted); font-size: 0.9rem; text-align: center; }
+footer { padding: 1.5rem; border-top: 1px solid var(--border); color: var(--muted); font-size: 0.85rem; text-align: center; }
+`
+
+const htmlSiteDark = `:root { --bg: #111827; --fg: #e5e7eb; --muted: #9ca3af; --link: #60a5fa; --border: #374151; --code: #1f2937; }`
+
+// htmlStylesheet returns the stylesheet with the configured dark mode
+func htmlStylesheet() string {
+	dark := ""
+	switch cfg.Theme.DarkMode {
+	case DarkModeDark:
+		dark = htmlSiteDark
+	case DarkModeAuto:
+		dark = "@media (prefers-color-scheme: dark) { " + htmlSiteDark + " }"
+	}
+	return strings.Replace(htmlSiteStyle, "__DARK__", dark, 1)
+}
+
+// WriteHTMLSite writes every page as a plain HTML document with the menu,
+// table of contents and a search form rendered in, for the html profile.
+func WriteHTMLSite(site SiteData, partials map[string]string, feeds []Feed) error {
+	for slug, page := range site.Pages {
+		path := filepath.Join(cfg.OutputDir, filepath.FromSlash(slug), "index.html")
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		doc := htmlDocument(slug, page, site.Menu, partials, feeds)
+		if err := os.WriteFile(path, []byte(minifyHTML(doc)), 0644); err != nil {
+			return err
+		}
+	}
+	notFound := PageData{Title: "Page not found", Content: fmt.Sprintf("<p>There is no page at this address. Try the <a href=\"%s\">site index</a>.</p>\n", pageHref(SiteIndexSlug)), NoIndex: true}
+	return os.WriteFile(filepath.Join(cfg.OutputDir, "404.html"), []byte(minifyHTML(htmlDocument("", notFound, site.Menu, partials, feeds))), 0644)
+}
+
+func htmlDocument(slug string, page PageData, menu []*MenuItem, partials map[string]string, feeds []Feed) string {
+	title := page.Title + " - " + cfg.Title
+	if slug == "/" {
+		title = cfg.Title
+	}
+	description := page.Description
+	if description == "" {
+		description = cfg.Description
+	}
+	chromeless := page.Layout == LayoutLanding || page.Layout == LayoutBare
+
+	var buf strings.Builder
+	buf.WriteString("<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n")
+	buf.WriteString("    <meta charset=\"UTF-8\">\n")
+	buf.WriteString("    <meta name=\"viewport\" content=\"width=device-width, initial-scale=1.0\">\n")
+	buf.WriteString(fmt.Sprintf("    <title>%s</title>\n", html.EscapeString(title)))
+	buf.WriteString(fmt.Sprintf("    <meta name=\"description\" content=\"%s\">\n", html.EscapeString(description)))
+	if slug != "" {
+		buf.WriteString(pageHeadTags(slug, &page))
+	} else {
+		buf.WriteString("    <meta name=\"robots\" content=\"noindex\">\n")
+	}
+	buf.WriteString(feedLinks(headFeeds(feeds, slug, page)))
+	buf.WriteString("    <style>" + htmlStylesheet() + "    </style>\n")
+	buf.WriteString(partials["before-head-end"])
+	buf.WriteString("</head>\n<body>\n")
+	buf.WriteString(partials["after-body-start"])
+	buf.WriteString("<a class=\"skip\" href=\"#content\">Skip to content</a>\n")
+
+	if page.Layout != LayoutBare {
+		buf.WriteString("<header>\n")
+		buf.WriteString(fmt.Sprintf("    <a class=\"title\" href=\"%s\">%s</a>\n", pageHref("/"), html.EscapeString(cfg.Title)))
+		host := ""
+		if u, err := url.Parse(cfg.BaseURL); err == nil {
+			host = u.Host
+		}
+		buf.WriteString("    <form role=\"search\" action=\"https://duckduckgo.com/\" method=\"get\">\n")
+		buf.WriteString("        <input type=\"search\" name=\"q\" aria-label=\"Search the site\" placeholder=\"Search\">\n")
+		buf.WriteString(fmt.Sprintf("        <input type=\"hidden\" name=\"sites\" value=\"%s\">\n", html.EscapeString(host)))
+		buf.WriteString("        <button type=\"submit\">Search</button>\n")
+		buf.WriteString("    </form>\n")
+		buf.WriteString("</header>\n")
+	}
+
+	buf.WriteString("<div class=\"layout\">\n")
+	if page.Layout != LayoutBare {
+		buf.WriteString("<nav class=\"site-nav\" aria-label=\"Site\">\n")
+		writeHTMLNav(&buf, menu, slug)
+		buf.WriteString(partials["sidebar-footer"])
+		buf.WriteString("</nav>\n")
+	}
+	buf.WriteString("<main id=\"content\">\n")
+	if !chromeless {
+		if len(page.Breadcrumbs) > 0 {
+			buf.WriteString("<nav class=\"breadcrumbs\" aria-label=\"Breadcrumb\">")
+			for _, link := range page.Breadcrumbs {
+				if link.Slug != "" {
+					buf.WriteString(fmt.Sprintf("<a href=\"%s\">%s</a> / ", pageHref(link.Slug), html.EscapeString(link.Title)))
+				} else {
+					buf.WriteString(html.EscapeString(link.Title) + " / ")
+				}
+			}
+			buf.WriteString("</nav>\n")
+		}
+		buf.WriteString(fmt.Sprintf("<h1>%s</h1>\n", html.EscapeString(page.Title)))
+		if !page.HideMeta {
+			writeHTMLPageMeta(&buf, page)
+		}
+	}
+	if !page.HideTOC && page.Layout == LayoutDefault && len(page.TOC) > 1 {
+		buf.WriteString("<nav class=\"toc\" aria-label=\"On this page\">\n<strong>On this page</strong>\n<ul>\n")
+		for _, entry := range page.TOC {
+			if entry.Level < 2 || entry.Level > 3 {
+				continue
+			}
+			indent := ""
+			if entry.Level == 3 {
+				indent = " style=\"margin-left: 1rem\""
+			}
+			buf.WriteString(fmt.Sprintf("<li%s><a href=\"#%s\">%s</a></li>\n", indent, html.EscapeString(entry.ID), html.EscapeString(entry.Title)))
+		}
+		buf.WriteString("</ul>\n</nav>\n")
+	}
+	layout := page.Layout
+	if layout == LayoutDefault {
+		layout = "default"
+	}
+	buf.WriteString("<article class=\"layout-" + layout + "\">\n")
+	buf.WriteString(page.Content)
+	buf.WriteString("\n</article>\n")
+	if !chromeless && len(page.Backlinks) > 0 {
+		buf.WriteString("<h2>Linked from</h2>\n<ul>\n")
+		for _, link := range page.Backlinks {
+			buf.WriteString(fmt.Sprintf("<li><a href=\"%s\">%s</a></li>\n", pageHref(link.Slug), html.EscapeString(link.Title)))
+		}
+		buf.WriteString("</ul>\n")
+	}
+	if !chromeless && len(page.Related) > 0 {
+		buf.WriteString("<h2>Related</h2>\n<ul>\n")
+		for _, link := range page.Related {
+			buf.WriteString(fmt.Sprintf("<li><a href=\"%s\">%s</a></li>\n", pageHref(link.Slug), html.EscapeString(link.Title)))
+		}
+		buf.WriteString("</ul>\n")
+	}
+	buf.WriteString("</main>\n</div>\n")
+	if page.Layout != LayoutBare {
+		buf.WriteString(fmt.Sprintf("<footer>%s &middot; <a href=\"%s\">Site index</a></footer>\n", html.EscapeString(cfg.Title), pageHref(SiteIndexSlug)))
+	}
+	buf.WriteString("</body>\n</html>\n")
+	return buf.String()
+}
+
+// writeHTMLPageMeta writes the dates, category, tags and other terms line of a page
+func writeHTMLPageMeta(buf *strings.Builder, page PageData) {
+	var parts []string
+	if dates := pageDates(page); dates != "" {
+		parts = append(parts, dates)
+	}
+	if page.Category != "" {
+		parts = append(parts, fmt.Sprintf("<a href=\"%s\">%s</a>", pageHref(CategoriesSlug+"/"+termSlug(page.Category)), html.EscapeString(page.Category)))
+	}
+	for _, tag := range page.Tags {
+		parts = append(parts, fmt.Sprintf("<a href=\"%s\">#%s</a>", pageHref(TagsSlug+"/"+termSlug(tag)), html.EscapeString(tag)))
+	}
+	for _, name := range sortedKeys(page.Terms) {
+		_, label := taxonomyNames(name)
+		for _, term := range page.Terms[name] {
+			parts = append(parts, fmt.Sprintf("%s: <a href=\"%s\">%s</a>", html.EscapeString(label), pageHref(taxonomySlug(name)+"/"+termSlug(term)), html.EscapeString(term)))
+		}
+	}
+	if page.Draft {
+		parts = append(parts, "<strong>Draft</strong>")
+	}
+	if len(parts) > 0 {
+		buf.WriteString("<p class=\"meta\">" + strings.Join(parts, " &middot; ") + "</p>\n")
+	}
+}
+
+// writeHTMLNav renders the menu as nested lists; folders are <details>
+// elements, open when they contain the current page or are set to expand.
+func writeHTMLNav(buf *strings.Builder, items []*MenuItem, current string) {
+	buf.WriteString("<ul>\n")
+	for _, item := range items {
+		switch {
+		case item.Separator:
+			buf.WriteString("<li role=\"separator\"><hr></li>\n")
+		case item.URL != "":
+			buf.WriteString(fmt.Sprintf("<li><a href=\"%s\">%s</a></li>\n", html.EscapeString(item.URL), html.EscapeString(item.Title)))
+		case item.IsFolder || len(item.Children) > 0:
+			open := ""
+			if item.Expand == ExpandAlways || (item.Expand != ExpandNever && menuContains(item, current)) {
+				open = " open"
+			}
+			label := html.EscapeString(item.Title)
+			if item.Slug != "" {
+				label = htmlNavLink(item, current)
+			}
+			buf.WriteString(fmt.Sprintf("<li><details%s><summary>%s</summary>\n", open, label))
+			writeHTMLNav(buf, item.Children, current)
+			buf.WriteString("</details></li>\n")
+		default:
+			buf.WriteString("<li>" + htmlNavLink(item, current) + "</li>\n")
+		}
+	}
+	buf.WriteString("</ul>\n")
+}
+
+func htmlNavLink(item *MenuItem, current string) string {
+	attr := ""
+	if item.Slug == current {
+		attr = " aria-current=\"page\""
+	}
+	return fmt.Sprintf("<a href=\"%s\"%s>%s</a>", pageHref(item.Slug), attr, html.EscapeString(item.Title))
+}
+
+// menuContains reports whether the page at slug is in the item's subtree
+func menuContains(item *MenuItem, slug string) bool {
+	if item.Slug == slug {
+		return true
+	}
+	for _, child := range item.Children {
+		if menuContains(child, slug) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"os/exec"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Image formats made from every processed image, besides resized copies in
+// its own format
+const (
+	ImageWebP = "webp"
+	ImageAVIF = "avif"
+)
+
+// imgTagRegex matches an <img> tag and imgSrcRegex its src attribute
+var (
+	imgTagRegex = regexp.MustCompile(`<img\s[^>]*>`)
+	imgSrcRegex = regexp.MustCompile(`\ssrc="([^"]*)"`)
+)
+
+// imageVariant is one file of a processed image
+type imageVariant struct {
+	Rel   string // output path, relative to the output folder
+	Width int
+}
+
+// processedImage holds the variants of one source image by format ("" for
+// its own format), smallest first, the last one at full size
+type processedImage struct {
+	Width, Height int
+	Variants      map[string][]imageVariant
+}
+
+// ProcessImages makes resized copies of the JPEG and PNG assets (rels,
+// relative to cfg.InputDir) shown by pages, plus WebP and AVIF versions when
+// their commands are configured, and turns the <img> tags into <picture>
+// elements offering them. Images that fail are left as they are. It returns
+// the files written.
+func ProcessImages(pages map[string]PageData, assets []string) []string {
+	if len(cfg.ImageWidths) == 0 {
+		return nil
+	}
+	if cfg.ImageWebPCommand == "" && cfg.ImageAVIFCommand == "" {
+		fmt.Println("Warning: image_widths is set but neither image_webp_command nor image_avif_command; only resized JPEG/PNG copies are made")
+	}
+	copied := make(map[string]bool, len(assets))
+	for _, rel := range assets {
+		copied[rel] = true
+	}
+	processed := make(map[string]*processedImage)
+	failed := make(map[string]bool)
+	count := 0
+	for slug, page := range pages {
+		content := imgTagRegex.ReplaceAllStringFunc(page.Content, func(tag string) string {
+			m := imgSrcRegex.FindStringSubmatch(tag)
+			if m == nil || strings.Contains(tag, " srcset=") {
+				return tag
+			}
+			rel := strings.TrimPrefix(html.UnescapeString(m[1]), basePath()+"/")
+			ext := strings.ToLower(path.Ext(rel))
+			if !copied[rel] || (ext != ".jpg" && ext != ".jpeg" && ext != ".png") || failed[rel] {
+				return tag
+			}
+			img := processed[rel]
+			if img == nil {
+				var err error
+				if img, err = processImage(rel); err != nil {
+					fmt.Printf("Warning: %s: %v\n", rel, err)
+					failed[rel] = true
+					return tag
+				}
+				processed[rel] = img
+				count++
+			}
+			return pictureTag(tag, img)
+		})
+		if content != page.Content {
+			page.Content = content
+			pages[slug] = page
+		}
+	}
+	if count > 0 {
+		fmt.Printf("Processed %d images\n", count)
+	}
+	var written []string
+	for _, rel := range sortedKeys(processed) {
+		for _, format := range []string{"", ImageAVIF, ImageWebP} {
+			for _, v := range processed[rel].Variants[format] {
+				if v.Rel != rel {
+					written = append(written, v.Rel)
+				}
+			}
+		}
+	}
+	return written
+}
+
+// processImage writes the variants of the image at rel next to its copy in
+// the output folder: name.480w.jpg, name.480w.webp, name.webp, ...
+func processImage(rel string) (*processedImage, error) {
+	src := contentFile(rel)
+	f, err := os.Open(src)
+	if err != nil {
+		return nil, err
+	}
+	orig, format, err := image.Decode(f)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+	bounds := orig.Bounds()
+	img := &processedImage{Width: bounds.Dx(), Height: bounds.Dy(), Variants: make(map[string][]imageVariant)}
+	stem := strings.TrimSuffix(rel, path.Ext(rel))
+
+	commands := map[string]string{ImageWebP: cfg.ImageWebPCommand, ImageAVIF: cfg.ImageAVIFCommand}
+	addVariant := func(file string, width int) error {
+		img.Variants[""] = append(img.Variants[""], imageVariant{Rel: file, Width: width})
+		for _, modern := range []string{ImageAVIF, ImageWebP} {
+			if commands[modern] == "" {
+				continue
+			}
+			out := strings.TrimSuffix(file, path.Ext(file)) + "." + modern
+			if err := convertImage(commands[modern], outputPath(file), outputPath(out)); err != nil {
+				return fmt.Errorf("converting to %s: %w", modern, err)
+			}
+			img.Variants[modern] = append(img.Variants[modern], imageVariant{Rel: out, Width: width})
+		}
+		return nil
+	}
+	for _, width := range cfg.ImageWidths {
+		if width >= img.Width {
+			continue
+		}
+		file := fmt.Sprintf("%s.%dw%s", stem, width, path.Ext(rel))
+		if err := writeImage(outputPath(file), resizeImage(orig, width), format); err != nil {
+			return nil, err
+		}
+		if err := addVariant(file, width); err != nil {
+			return nil, err
+		}
+	}
+	if err := addVariant(rel, img.Width); err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+func outputPath(rel string) string {
+	return outputFile(rel)
+}
+
+// convertImage runs an image command, replacing {in}, {out} and {quality}
+// in its arguments
+func convertImage(command, in, out string) error {
+	return runCommand(command, strings.NewReplacer("{in}", in, "{out}", out, "{quality}", strconv.Itoa(cfg.ImageQuality)))
+}
+
+// runCommand runs a configured command line, split at spaces, with the
+// placeholders of its arguments replaced
+func runCommand(command string, placeholders *strings.Replacer) error {
+	args := strings.Fields(command)
+	for i, arg := range args {
+		args[i] = placeholders.Replace(arg)
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+func writeImage(file string, img image.Image, format string) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if format == "png" {
+		err = png.Encode(f, img)
+	} else {
+		err = jpeg.Encode(f, img, &jpeg.Options{Quality: cfg.ImageQuality})
+	}
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// resizeImage scales src down to width, averaging the source pixels that
+// fall into each target pixel
+func resizeImage(src image.Image, width int) image.Image {
+	b := src.Bounds()
+	height := max(1, b.Dy()*width/b.Dx())
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0, y1 := b.Min.Y+y*b.Dy()/height, b.Min.Y+(y+1)*b.Dy()/height
+		for x := 0; x < width; x++ {
+			x0, x1 := b.Min.X+x*b.Dx()/width, b.Min.X+(x+1)*b.Dx()/width
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < max(y1, y0+1); sy++ {
+				for sx := x0; sx < max(x1, x0+1); sx++ {
+					c := color.NRGBAModel.Convert(src.At(sx, sy)).(color.NRGBA)
+					r, g, bl, a = r+uint64(c.R), g+uint64(c.G), bl+uint64(c.B), a+uint64(c.A)
+					n++
+				}
+			}
+			dst.SetNRGBA(x, y, color.NRGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(bl / n), A: uint8(a / n)})
+		}
+	}
+	return dst
+}
+
+// pictureTag wraps an <img> tag in a <picture> offering the variants of img
+func pictureTag(tag string, img *processedImage) string {
+	srcset := func(variants []imageVariant) string {
+		list := make([]string, len(variants))
+		for i, v := range variants {
+			list[i] = fmt.Sprintf("%s/%s %dw", basePath(), v.Rel, v.Width)
+		}
+		return html.EscapeString(strings.Join(list, ", "))
+	}
+	sizes := fmt.Sprintf("(max-width: %dpx) 100vw, %dpx", img.Width, img.Width)
+	if cfg.ImageSizes != "" {
+		sizes = cfg.ImageSizes
+	}
+	attrs := fmt.Sprintf(` srcset="%s" sizes="%s"`, srcset(img.Variants[""]), html.EscapeString(sizes))
+	if !strings.Contains(tag, " width=") && !strings.Contains(tag, " height=") {
+		attrs += fmt.Sprintf(` width="%d" height="%d"`, img.Width, img.Height)
+	}
+	if !strings.Contains(tag, " loading=") {
+		attrs += ` loading="lazy"`
+	}
+	closing := ">"
+	if strings.HasSuffix(tag, "/>") {
+		closing = "/>"
+	}
+	var buf strings.Builder
+	buf.WriteString("<picture>")
+	for _, format := range []string{ImageAVIF, ImageWebP} {
+		if variants := img.Variants[format]; len(variants) > 0 {
+			buf.WriteString(fmt.Sprintf(`<source type="image/%s" srcset="%s" sizes="%s">`, format, srcset(variants), html.EscapeString(sizes)))
+		}
+	}
+	buf.WriteString(strings.TrimSuffix(strings.TrimSuffix(tag, ">"), "/") + attrs + closing)
+	buf.WriteString("</picture>")
+	return buf.String()
+}
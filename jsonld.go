@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// AddStructuredData builds the schema.org JSON-LD of every page: an Article
+// (BlogPosting in the posts section) for dated pages, otherwise a WebPage,
+// plus a BreadcrumbList of its Breadcrumbs.
+func AddStructuredData(pages map[string]PageData) {
+	postsPrefix := ""
+	if cfg.PostsSection != "" {
+		postsPrefix = normalizeSlug("/"+strings.Trim(cfg.PostsSection, "/")) + "/"
+	}
+	for slug, page := range pages {
+		url := canonicalURL(slug, page)
+		doc := map[string]interface{}{
+			"@type":    "WebPage",
+			"headline": page.Title,
+			"url":      url,
+		}
+		if page.Description != "" {
+			doc["description"] = page.Description
+		}
+		if page.Image != "" {
+			doc["image"] = page.Image
+		}
+		if !page.PublishedAt.IsZero() {
+			doc["@type"] = "Article"
+			if postsPrefix != "" && strings.HasPrefix(slug, postsPrefix) {
+				doc["@type"] = "BlogPosting"
+			}
+			doc["datePublished"] = page.PublishedAt.Format("2006-01-02")
+			doc["dateModified"] = page.PublishedAt.Format("2006-01-02")
+			if !page.UpdatedAt.IsZero() {
+				doc["dateModified"] = page.UpdatedAt.Format("2006-01-02")
+			}
+			doc["mainEntityOfPage"] = url
+			doc["publisher"] = map[string]string{"@type": "Organization", "name": cfg.Title}
+			author := page.Author
+			if author == "" {
+				author = cfg.Author
+			}
+			if author != "" {
+				doc["author"] = map[string]string{"@type": "Person", "name": author}
+			} else {
+				doc["author"] = doc["publisher"]
+			}
+		}
+		graph := []interface{}{doc}
+
+		if len(page.Breadcrumbs) > 0 {
+			var crumbs []map[string]interface{}
+			trail := append(append([]PageLink{}, page.Breadcrumbs...), PageLink{Title: page.Title, Slug: slug})
+			for _, link := range trail {
+				if link.Slug == "" {
+					continue // folder without a page of its own
+				}
+				crumbs = append(crumbs, map[string]interface{}{"@type": "ListItem", "position": len(crumbs) + 1, "name": link.Title, "item": preferredURL(link.Slug)})
+			}
+			graph = append(graph, map[string]interface{}{"@type": "BreadcrumbList", "itemListElement": crumbs})
+		}
+
+		data, _ := json.Marshal(map[string]interface{}{"@context": "https://schema.org", "@graph": graph})
+		page.JSONLD = string(data)
+		pages[slug] = page
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Hero is the banner of a landing page, from `hero` frontmatter:
+//
+//	hero:
+//	  title: Docs
+//	  tagline: Everything about running your own server
+//	  image: ./logo.png
+//	  actions:
+//	    - {text: Get started, link: /guide, style: primary}
+//	    - {text: GitHub, link: https://github.com/...}
+type Hero struct {
+	Title   string       `yaml:"title"` // defaults to the page title
+	Tagline string       `yaml:"tagline"`
+	Image   string       `yaml:"image"`
+	Actions []HeroAction `yaml:"actions"`
+}
+
+// HeroAction is a call-to-action button of the hero
+type HeroAction struct {
+	Text  string `yaml:"text"`
+	Link  string `yaml:"link"`
+	Style string `yaml:"style"` // primary (default) or secondary
+}
+
+// Feature is a card of the feature grid, from `features` frontmatter
+type Feature struct {
+	Title   string `yaml:"title"`
+	Details string `yaml:"details"`
+	Icon    string `yaml:"icon"` // Lineicons name, e.g. rocket
+	Link    string `yaml:"link"`
+}
+
+// landingSections renders the hero and feature cards of a landing page
+// from its frontmatter, to go above the markdown content. Pages without
+// `hero` and `features` get an empty string.
+func landingSections(meta map[string]interface{}, title string) (string, error) {
+	var hero *Hero
+	var features []Feature
+	if err := decodeFrontmatterKey(meta, "hero", &hero); err != nil {
+		return "", err
+	}
+	if err := decodeFrontmatterKey(meta, "features", &features); err != nil {
+		return "", err
+	}
+
+	var buf strings.Builder
+	if hero != nil {
+		if hero.Title == "" {
+			hero.Title = title
+		}
+		buf.WriteString("<section class=\"landing-hero not-prose\">\n")
+		if hero.Image != "" {
+			buf.WriteString(fmt.Sprintf("<img class=\"landing-hero-image\" src=\"%s\" alt=\"\">\n", html.EscapeString(hero.Image)))
+		}
+		buf.WriteString(fmt.Sprintf("<h1 class=\"landing-hero-title\">%s</h1>\n", html.EscapeString(hero.Title)))
+		if hero.Tagline != "" {
+			buf.WriteString(fmt.Sprintf("<p class=\"landing-hero-tagline\">%s</p>\n", html.EscapeString(hero.Tagline)))
+		}
+		if len(hero.Actions) > 0 {
+			buf.WriteString("<div class=\"landing-actions\">")
+			for _, a := range hero.Actions {
+				style := a.Style
+				if style != "secondary" {
+					style = "primary"
+				}
+				buf.WriteString(fmt.Sprintf("<a class=\"landing-button landing-button-%s\" href=\"%s\">%s</a>", style, html.EscapeString(landingLink(a.Link)), html.EscapeString(a.Text)))
+			}
+			buf.WriteString("</div>\n")
+		}
+		buf.WriteString("</section>\n")
+	}
+	if len(features) > 0 {
+		buf.WriteString("<div class=\"landing-features not-prose\">\n")
+		for _, f := range features {
+			tag, attrs := "div", ""
+			if f.Link != "" {
+				tag, attrs = "a", fmt.Sprintf(" href=\"%s\"", html.EscapeString(landingLink(f.Link)))
+			}
+			buf.WriteString(fmt.Sprintf("<%s class=\"landing-feature\"%s>", tag, attrs))
+			if f.Icon != "" {
+				buf.WriteString(fmt.Sprintf("<i class=\"lni lni-%s\" aria-hidden=\"true\"></i>", html.EscapeString(strings.TrimPrefix(f.Icon, "lni-"))))
+			}
+			buf.WriteString(fmt.Sprintf("<h3>%s</h3>", html.EscapeString(f.Title)))
+			if f.Details != "" {
+				buf.WriteString(fmt.Sprintf("<p>%s</p>", html.EscapeString(f.Details)))
+			}
+			buf.WriteString(fmt.Sprintf("</%s>\n", tag))
+		}
+		buf.WriteString("</div>\n")
+	}
+	return buf.String(), nil
+}
+
+// decodeFrontmatterKey decodes the frontmatter value at key into v, leaving
+// v alone when the key is missing
+func decodeFrontmatterKey(meta map[string]interface{}, key string, v interface{}) error {
+	val, ok := meta[key]
+	if !ok {
+		return nil
+	}
+	data, err := yaml.Marshal(val)
+	if err != nil {
+		return err
+	}
+	if err := yaml.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid %s frontmatter: %w", key, err)
+	}
+	return nil
+}
+
+// landingLink points site paths (/guide#setup) at their page route; other
+// links are kept as they are
+func landingLink(link string) string {
+	if !strings.HasPrefix(link, "/") || strings.HasPrefix(link, "//") {
+		return link
+	}
+	slug, hash, _ := strings.Cut(link, "#")
+	if slug = strings.TrimSuffix(slug, "/"); slug == "" {
+		slug = "/"
+	}
+	href := pageHref(normalizeSlug(slug))
+	if hash != "" {
+		href += "#" + hash
+	}
+	return href
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// externalLinks is a goldmark extension that marks links leaving the site
+// with target/rel attributes and an optional icon class.
+type externalLinks struct {
+	Target string
+	Rel    string
+	Class  string
+}
+
+func (e *externalLinks) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithASTTransformers(util.Prioritized(e, 500)))
+}
+
+func (e *externalLinks) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		var dest string
+		switch link := n.(type) {
+		case *ast.Link:
+			dest = string(link.Destination)
+		case *ast.AutoLink:
+			if link.AutoLinkType != ast.AutoLinkURL {
+				return ast.WalkContinue, nil
+			}
+			dest = string(link.URL(source))
+		default:
+			return ast.WalkContinue, nil
+		}
+		if !isExternalURL(dest) {
+			return ast.WalkContinue, nil
+		}
+		if e.Target != "" {
+			n.SetAttributeString("target", []byte(e.Target))
+		}
+		if e.Rel != "" {
+			n.SetAttributeString("rel", []byte(e.Rel))
+		}
+		if e.Class != "" {
+			n.SetAttributeString("class", []byte(e.Class))
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+// isExternalURL reports whether dest points to a different host than BaseURL.
+// Relative, hash and mailto links are always considered internal.
+func isExternalURL(dest string) bool {
+	if strings.HasPrefix(dest, "www.") {
+		dest = "http://" + dest
+	}
+	u, err := url.Parse(dest)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	base, err := url.Parse(cfg.BaseURL)
+	if err != nil {
+		return true
+	}
+	return !strings.EqualFold(u.Hostname(), base.Hostname())
+}
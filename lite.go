@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// LiteDir is the output folder of the lightweight page variants
+const LiteDir = "lite"
+
+// appLinkRegex matches links to app routes ("#/guide#setup"), which lite
+// pages have no router to follow
+var appLinkRegex = regexp.MustCompile(`href="#(/[^"#]*)(#[^"]*)?"`)
+
+// liteURL returns the absolute URL of the lite variant of a page
+func liteURL(slug string) string {
+	return siteURL(path.Join(LiteDir, slug) + "/")
+}
+
+// WriteLitePages writes a stripped-down copy of every page to
+// lite/<slug>/index.html: inline CSS, no scripts, partials or web fonts,
+// pointing back to the full page as canonical.
+func WriteLitePages(pages map[string]PageData) error {
+	for slug, page := range pages {
+		file := filepath.Join(cfg.OutputDir, LiteDir, filepath.FromSlash(slug), "index.html")
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(file, []byte(minifyHTML(liteDocument(slug, page))), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func liteDocument(slug string, page PageData) string {
+	full := canonicalURL(slug, page)
+	description := page.Description
+	if description == "" {
+		description = cfg.Description
+	}
+
+	var buf strings.Builder
+	buf.WriteString("<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n")
+	buf.WriteString("<meta charset=\"UTF-8\">\n")
+	buf.WriteString("<meta name=\"viewport\" content=\"width=device-width, initial-scale=1.0\">\n")
+	buf.WriteString(fmt.Sprintf("<title>%s - %s</title>\n", html.EscapeString(page.Title), html.EscapeString(cfg.Title)))
+	buf.WriteString(fmt.Sprintf("<meta name=\"description\" content=\"%s\">\n", html.EscapeString(description)))
+	buf.WriteString(fmt.Sprintf("<link rel=\"canonical\" href=\"%s\">\n", html.EscapeString(full)))
+	if page.NoIndex {
+		buf.WriteString("<meta name=\"robots\" content=\"noindex\">\n")
+	}
+	if page.JSONLD != "" {
+		buf.WriteString("<script type=\"application/ld+json\">" + page.JSONLD + "</script>\n")
+	}
+	buf.WriteString("<style>" + htmlStylesheet() + "main { max-width: 48rem; margin: 0 auto; }\n</style>\n")
+	buf.WriteString("</head>\n<body>\n<main>\n")
+	buf.WriteString(fmt.Sprintf("<p><a href=\"%s\">%s</a></p>\n", html.EscapeString(liteURL("/")), html.EscapeString(cfg.Title)))
+	if page.Layout != LayoutLanding && page.Layout != LayoutBare {
+		buf.WriteString(fmt.Sprintf("<h1>%s</h1>\n", html.EscapeString(page.Title)))
+		if dates := pageDates(page); dates != "" && !page.HideMeta {
+			buf.WriteString("<p class=\"meta\">" + dates + "</p>\n")
+		}
+	}
+	buf.WriteString("<article class=\"layout-default\">\n")
+	buf.WriteString(liteLinks(page.Content))
+	buf.WriteString("\n</article>\n")
+	buf.WriteString(fmt.Sprintf("<footer><a href=\"%s\">Full version</a></footer>\n", html.EscapeString(full)))
+	buf.WriteString("</main>\n</body>\n</html>\n")
+	return buf.String()
+}
+
+// liteLinks points the app links of content to the lite copies of the pages
+func liteLinks(content string) string {
+	return appLinkRegex.ReplaceAllStringFunc(content, func(m string) string {
+		parts := appLinkRegex.FindStringSubmatch(m)
+		slug := strings.TrimSuffix(html.UnescapeString(parts[1]), "/index.html")
+		if slug = strings.TrimSuffix(slug, "/"); slug == "" {
+			slug = "/"
+		}
+		return `href="` + html.EscapeString(liteURL(slug)) + parts[2] + `"`
+	})
+}
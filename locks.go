@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ContentLocks holds the approved content of pages with `locked: true`
+// frontmatter, by source path. A locked page may only change together with
+// its `locked_approved_by`; the file is meant to be committed with the
+// content. Removing or renaming a locked page is approved in the file
+// itself, by adding its path and approver to Removals.
+type ContentLocks struct {
+	Pages    map[string]ContentLock `json:"pages"`
+	Removals map[string]string      `json:"removals,omitempty"` // path -> approved by
+
+	path   string
+	seen   map[string]bool
+	errors []string
+	dirty  bool
+}
+
+// ContentLock is the approved state of a locked page
+type ContentLock struct {
+	Hash       string `json:"hash"` // of the content below the frontmatter
+	ApprovedBy string `json:"approved_by,omitempty"`
+}
+
+// LoadContentLocks reads the lock file at path; an empty path disables locking
+func LoadContentLocks(path string) (*ContentLocks, error) {
+	if path == "" {
+		return nil, nil
+	}
+	l := &ContentLocks{Pages: make(map[string]ContentLock), path: path, seen: make(map[string]bool)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, l); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if l.Pages == nil {
+		l.Pages = make(map[string]ContentLock)
+	}
+	return l, nil
+}
+
+// Check compares a page (rel relative to cfg.InputDir) with its approved
+// content. Pages locked for the first time are recorded, and a new
+// locked_approved_by approves the current content, or unlocks the page when
+// `locked` was removed. Changes without one are collected as errors; so are
+// changes to pages unlocked without approval.
+func (l *ContentLocks) Check(rel string, source []byte, meta map[string]interface{}) {
+	if l == nil {
+		return
+	}
+	l.seen[rel] = true
+	locked, _ := meta["locked"].(bool)
+	prev, known := l.Pages[rel]
+	approvedBy, _ := meta["locked_approved_by"].(string)
+	approvedBy = strings.TrimSpace(approvedBy)
+	hash := hashBytes(stripFrontmatter(source))
+	switch {
+	case !known:
+		if !locked {
+			return
+		}
+	case approvedBy != "" && approvedBy != prev.ApprovedBy:
+		if !locked {
+			delete(l.Pages, rel)
+			l.dirty = true
+			return
+		}
+	case prev.Hash != hash:
+		l.errors = append(l.errors, fmt.Sprintf("%s is locked and changed without a new locked_approved_by (last approved by %s)", rel, orNone(prev.ApprovedBy)))
+		return
+	default:
+		return
+	}
+	l.Pages[rel] = ContentLock{Hash: hash, ApprovedBy: approvedBy}
+	l.dirty = true
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "nobody"
+	}
+	return s
+}
+
+// Save fails with the unapproved changes and removals, if any; otherwise it
+// writes the lock file when pages were locked, approved or removed
+func (l *ContentLocks) Save() error {
+	if l == nil {
+		return nil
+	}
+	for rel, lock := range l.Pages {
+		if l.seen[rel] {
+			continue
+		}
+		if strings.TrimSpace(l.Removals[rel]) == "" {
+			l.errors = append(l.errors, fmt.Sprintf("%s is locked and was removed or skipped without an approval in the removals of %s (last approved by %s)", rel, l.path, orNone(lock.ApprovedBy)))
+			continue
+		}
+		delete(l.Pages, rel)
+		l.dirty = true
+	}
+	for rel := range l.Removals {
+		if _, locked := l.Pages[rel]; !locked || l.seen[rel] {
+			delete(l.Removals, rel) // carried out, or the page is still there
+			l.dirty = true
+		}
+	}
+	if len(l.errors) > 0 {
+		sort.Strings(l.errors)
+		return fmt.Errorf("locked pages changed:\n  %s", strings.Join(l.errors, "\n  "))
+	}
+	if !l.dirty {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return err
+	}
+	data, _ := json.MarshalIndent(l, "", "  ")
+	return os.WriteFile(l.path, append(data, '\n'), 0644)
+}
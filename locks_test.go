@@ -0,0 +1,54 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestContentLocksCheck(t *testing.T) {
+	approved := ContentLock{Hash: hashBytes([]byte("approved\n")), ApprovedBy: "ann"}
+	tests := []struct {
+		name       string
+		prev       *ContentLock
+		body       string
+		meta       map[string]interface{}
+		want       *ContentLock
+		wantErrors int
+	}{
+		{"unlocked page", nil, "text\n", nil, nil, 0},
+		{"newly locked", nil, "text\n", map[string]interface{}{"locked": true, "locked_approved_by": " bob "},
+			&ContentLock{Hash: hashBytes([]byte("text\n")), ApprovedBy: "bob"}, 0},
+		{"unchanged", &approved, "approved\n", map[string]interface{}{"locked": true, "locked_approved_by": "ann"}, &approved, 0},
+		{"frontmatter change only", &approved, "---\ntitle: New\n---\napproved\n", map[string]interface{}{"locked": true, "locked_approved_by": "ann"}, &approved, 0},
+		{"changed without approval", &approved, "edited\n", map[string]interface{}{"locked": true}, &approved, 1},
+		{"changed with the old approver", &approved, "edited\n", map[string]interface{}{"locked": true, "locked_approved_by": "ann"}, &approved, 1},
+		{"changed with a new approver", &approved, "edited\n", map[string]interface{}{"locked": true, "locked_approved_by": "bob"},
+			&ContentLock{Hash: hashBytes([]byte("edited\n")), ApprovedBy: "bob"}, 0},
+		{"unlocked with approval", &approved, "edited\n", map[string]interface{}{"locked_approved_by": "bob"}, nil, 0},
+		{"unlocked without approval", &approved, "edited\n", nil, &approved, 1},
+	}
+	for _, tt := range tests {
+		l := &ContentLocks{Pages: make(map[string]ContentLock), seen: make(map[string]bool)}
+		if tt.prev != nil {
+			l.Pages["page.md"] = *tt.prev
+		}
+		l.Check("page.md", []byte(tt.body), tt.meta)
+
+		got, ok := l.Pages["page.md"]
+		switch {
+		case tt.want == nil && ok:
+			t.Errorf("%s: page locked as %+v, want no lock", tt.name, got)
+		case tt.want != nil && !reflect.DeepEqual(got, *tt.want):
+			t.Errorf("%s: lock = %+v, want %+v", tt.name, got, *tt.want)
+		}
+		if len(l.errors) != tt.wantErrors {
+			t.Errorf("%s: errors = %q, want %d", tt.name, l.errors, tt.wantErrors)
+		}
+		if !l.seen["page.md"] {
+			t.Errorf("%s: page not marked as seen", tt.name)
+		}
+	}
+
+	var none *ContentLocks
+	none.Check("page.md", nil, nil) // locking disabled
+}
@@ -10,56 +10,143 @@ import (
 	"strings"
 )
 
-func main() {
+// generate renders the site from cfg.InputDir into the empty cfg.OutputDir
+func generate() error {
 	fmt.Println("--- BUILDING OPTIMIZED SITE ---")
 
-	if _, err := os.Stat(InputDir); os.IsNotExist(err) {
-		fmt.Println("Error: 'content' folder missing.")
-		return
+	if _, err := os.Stat(cfg.InputDir); os.IsNotExist(err) {
+		return fmt.Errorf("'%s' folder missing", cfg.InputDir)
+	}
+	waitAbandonedRenders() // they read the glossary and shortcodes reloaded below
+	if err := LoadGlossary(cfg.GlossaryFile); err != nil {
+		return fmt.Errorf("loading glossary: %w", err)
+	}
+	if err := LoadShortcodes(cfg.TemplatesDir); err != nil {
+		return fmt.Errorf("loading shortcodes: %w", err)
 	}
-	os.RemoveAll(OutputDir)
-	os.Mkdir(OutputDir, 0755)
 
 	site := SiteData{
-		Pages: make(map[string]PageData),
-		Menu:  []*MenuItem{},
+		Pages:   make(map[string]PageData),
+		SlugMap: make(map[string]string),
 	}
+	menuRoot := &MenuItem{IsFolder: true, Children: []*MenuItem{}}
 	var xmlUrls []string
+	termUsage := make(map[string][]string)
+	dirMetas := make(map[string]*DirMeta)
+	cascades := make(map[string]map[string]interface{}) // folder -> `cascade` of its _index.md
+	usedSnippets := make(map[string]bool)
+	var assets []string
+	var sections []string // slugs of the _index.md pages
+	drafts, scheduled := 0, 0
+	buildTime := siteTime()
+	if cfg.ReleaseAt != "" {
+		fmt.Printf("Building the release of %s\n", buildTime.Format("2006-01-02 15:04 MST"))
+	}
+	summarizer := NewSummarizer(cfg.SummarizeCommand, cfg.SummarizeEndpoint, cfg.SummaryCacheFile)
+
+	codeOwners, err := LoadCodeOwners(cfg.CodeOwnersFile)
+	if err != nil {
+		return fmt.Errorf("loading CODEOWNERS: %w", err)
+	}
+	locks, err := LoadContentLocks(cfg.LockFile)
+	if err != nil {
+		return fmt.Errorf("loading content locks: %w", err)
+	}
 
-	err := filepath.WalkDir(InputDir, func(path string, d fs.DirEntry, err error) error {
+	cache := NewBuildCache(cfg.BuildCacheFile)
+	snippets, err := LoadSnippets(cache, usedSnippets)
+	if err != nil {
+		return fmt.Errorf("loading snippets: %w", err)
+	}
+	site.Snippets = snippets
+
+	err = filepath.WalkDir(cfg.InputDir, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
 		if d.IsDir() {
+			if path == filepath.Join(cfg.InputDir, SnippetsDir) {
+				return filepath.SkipDir
+			}
+			dm, err := LoadDirMeta(path)
+			if err != nil {
+				return err
+			}
+			if dm != nil {
+				dirMetas[strings.TrimPrefix(contentRel(path), ".")] = dm
+			}
+			cascade, err := LoadCascade(path)
+			if err != nil {
+				return err
+			}
+			if cascade != nil {
+				cascades[strings.TrimPrefix(contentRel(path), ".")] = cascade
+			}
 			return nil
 		}
 		if filepath.Ext(path) != ".md" {
+			if isAsset(d.Name()) {
+				assets = append(assets, contentRel(path))
+			}
 			return nil
 		}
 
 		// Calculate Slugs
-		relPath, _ := filepath.Rel(InputDir, path)
-		relPath = filepath.ToSlash(relPath)
+		relPath := contentRel(path)
 		filename := strings.TrimSuffix(filepath.Base(path), ".md")
-		dir := filepath.Dir(relPath)
+		section := filename == SectionIndex
+		if section {
+			filename = "index"
+		}
+		dir := filepath.ToSlash(filepath.Dir(relPath)) // also for index pages of nested folders
 		if dir == "." {
 			dir = ""
 		}
 
-		var slug string
-		if dir == "" && filename == "index" {
-			slug = "/"
-		} else if filename == "index" {
-			slug = "/" + dir
-		} else {
-			slug = "/" + filepath.ToSlash(filepath.Join(dir, filename))
+		slug := pageSlug(relPath)
+		if normalized := normalizeSlug(slug); normalized != slug {
+			site.SlugMap[slug] = normalized
+			slug = normalized
+		}
+		if _, exists := site.Pages[slug]; exists {
+			fmt.Printf("Warning: %s: slug %s is already used by another page, skipping\n", path, slug)
+			return nil
 		}
 
 		// Read & Process Content
-		source, _ := os.ReadFile(path)
-		result, err := ProcessMarkdown(source)
+		info, err := d.Info()
 		if err != nil {
-			return fmt.Errorf("failed to process %s: %w", path, err)
+			return err
+		}
+		source, warning := readMarkdown(path)
+		if warning != "" {
+			fmt.Printf("Warning: %s: %s\n", path, warning)
+		}
+		if source == nil {
+			return nil
+		}
+		result, err := cache.Render(relPath, source)
+		if err != nil {
+			fmt.Printf("Warning: %s: %v, skipping it\n", path, err)
+			return nil
+		}
+		for _, w := range result.Warnings {
+			fmt.Printf("Warning: %s: %s\n", path, w)
+		}
+		markSnippetUse(usedSnippets, result)
+		locks.Check(relPath, source, result.Meta)
+
+		if dm := dirMetas[dir]; dm != nil {
+			if result.Meta == nil {
+				result.Meta = make(map[string]interface{})
+			}
+			dm.ApplyDefaults(result.Meta)
+		}
+		if len(cascades) > 0 {
+			if result.Meta == nil {
+				result.Meta = make(map[string]interface{})
+			}
+			applyCascades(result.Meta, dir, section, cascades)
 		}
 
 		// Helper to safely get metadata
@@ -81,112 +168,581 @@ func main() {
 			return 0
 		}
 
+		getBool := func(key string, def bool) bool {
+			if val, ok := result.Meta[key].(bool); ok {
+				return val
+			}
+			return def
+		}
+
+		draft := getBool("draft", false)
+		if draft && !cfg.Drafts {
+			drafts++
+			return nil
+		}
+
+		description := result.Description
+		var keywords []string
+		if summarizer != nil && getString("description") == "" {
+			summary, err := summarizer.Summarize(plainText(result.HTML))
+			if err != nil {
+				fmt.Printf("Warning: %s: summarizer failed: %v\n", path, err)
+			} else {
+				if summary.Summary != "" {
+					description = summary.Summary
+				}
+				keywords = summary.Keywords
+			}
+		}
+
+		getExtra := func() map[string]any {
+			var extra map[string]any
+			for key, val := range result.Meta {
+				if _, taxonomy := cfg.Taxonomies[key]; frontmatterKeys[key] || taxonomy {
+					continue
+				}
+				if extra == nil {
+					extra = make(map[string]any)
+				}
+				extra[key] = jsonValue(val)
+			}
+			return extra
+		}
+
+		getMap := func(key string) map[string]string {
+			raw, ok := jsonValue(result.Meta[key]).(map[string]interface{})
+			if !ok || len(raw) == 0 {
+				return nil
+			}
+			m := make(map[string]string, len(raw))
+			for k, v := range raw {
+				m[fmt.Sprintf("%v", k)] = fmt.Sprintf("%v", v)
+			}
+			return m
+		}
+
 		published := getString("published on")
 		updated := getString("updated on")
+		publishedAt, ok := parseDate(published)
+		if published != "" && !ok {
+			fmt.Printf("Warning: %s: unrecognized date %q in published on\n", path, published)
+		}
+		updatedAt, ok := parseDate(updated)
+		if updated != "" && !ok {
+			fmt.Printf("Warning: %s: unrecognized date %q in updated on\n", path, updated)
+		}
+		reviewDue := getString("review_due")
+		reviewDueAt, ok := parseDate(reviewDue)
+		if reviewDue != "" && !ok {
+			fmt.Printf("Warning: %s: unrecognized date %q in review_due\n", path, reviewDue)
+		}
+		if publishedAt.After(buildTime) && !cfg.Future {
+			scheduled++
+			return nil
+		}
 		category := getString("category")
 		title := getString("title")
 		weight := getInt("weight")
+		if _, ok := result.Meta["weight"]; !ok {
+			weight = getInt("order") // same as weight
+		}
+		for _, key := range []string{"weight", "order"} {
+			if val, ok := result.Meta[key]; ok && getInt(key) == 0 && fmt.Sprint(val) != "0" {
+				fmt.Printf("Warning: %s: %s must be a whole number, got %q\n", path, key, fmt.Sprint(val))
+			}
+		}
+		freq := getString("changefreq")
+		if freq != "" && !validChangefreq[freq] {
+			fmt.Printf("Warning: %s: invalid changefreq %q, ignoring it\n", path, freq)
+			freq = ""
+		}
+		layout := getString("layout")
+		if !validLayout(layout) {
+			fmt.Printf("Warning: %s: unknown layout %q, using default\n", path, layout)
+			layout = LayoutDefault
+		}
+
+		contentType := getString("type")
+		if contentType == "" {
+			contentType = sectionOf(slug)
+		}
+		typed, err := DecodeFrontmatter(contentType, result.Meta)
+		if err != nil {
+			fmt.Printf("Warning: %s: %v\n", path, err)
+		}
+
+		var terms map[string][]string
+		for name := range cfg.Taxonomies {
+			if values := parseTerms(result.Meta[name]); len(values) > 0 {
+				if terms == nil {
+					terms = make(map[string][]string)
+				}
+				terms[name] = values
+			}
+		}
+		owners := parseTerms(result.Meta["owner"])
+		if len(owners) == 0 {
+			owners = codeOwners.Owners(path)
+		}
 
 		if title == "" {
-			title = strings.Title(strings.ReplaceAll(filename, "-", " "))
+			title = titleCase(filename)
+			if section && dir != "" {
+				title = titleCase(filepath.Base(dir))
+			}
 			if slug == "/" {
 				title = "Home"
 			}
 		}
 
+		content := result.HTML
+		if layout == LayoutLanding {
+			sections, err := landingSections(result.Meta, title)
+			if err != nil {
+				fmt.Printf("Warning: %s: %v\n", path, err)
+			}
+			content = sections + content
+		} else if result.Meta["hero"] != nil || result.Meta["features"] != nil {
+			fmt.Printf("Warning: %s: hero and features are only shown with layout: landing\n", path)
+		}
+
 		// Build Site Data
 		site.Pages[slug] = PageData{
 			Title:       title,
-			Content:     result.HTML,
+			Content:     resolveAssetLinks(content, dir),
 			TOC:         result.TOC,
 			Published:   published,
 			Updated:     updated,
+			PublishedAt: publishedAt,
+			UpdatedAt:   updatedAt,
+			ModTime:     info.ModTime(),
 			Category:    category,
-			Description: result.Description,
+			Tags:        parseTerms(result.Meta["tags"]),
+			Terms:       terms,
+			Description: description,
 			Weight:      weight,
+			Layout:      layout,
+			Draft:       draft,
+			MenuTitle:   getString("menu_title"),
+			HideTOC:     !getBool("toc", true),
+			HideMeta:    !getBool("show_meta", true),
+			Keywords:    keywords,
+			Meta:        absolutizeMeta(getMap("meta")),
+			Changefreq:  freq,
+			NoIndex:     getBool("noindex", false),
+			Canonical:   absoluteLink(getString("canonical")),
+			Image:       absoluteLink(assetLink(getString("image"), dir)),
+			Author:      getString("author"),
+			Extra:       getExtra(),
+			Type:        contentType,
+			Data:        typed,
+			Aliases:     append(parseTerms(result.Meta["aliases"]), parseTerms(result.Meta["previously"])...),
+			Short:       shortLink(getString("short")),
+			Owners:      owners,
+			ReviewedBy:  getString("reviewed_by"),
+			ReviewDue:   reviewDue,
+			ReviewDueAt: reviewDueAt,
+			EditURL:     editURL(relPath),
+		}
+
+		for _, term := range result.Terms {
+			termUsage[term] = append(termUsage[term], slug)
 		}
 
 		parts := strings.Split(strings.TrimSuffix(relPath, ".md"), "/")
-		site.Menu = addMenuItem(site.Menu, parts, slug, title, weight)
-		xmlUrls = append(xmlUrls, slug)
+		if section {
+			parts[len(parts)-1] = SectionIndex
+			sections = append(sections, slug)
+		}
+		menuTitle := getString("menu_title")
+		if menuTitle == "" {
+			menuTitle = title
+		}
+		addMenuItem(menuRoot, parts, slug, menuTitle, weight)
+		if site.Pages[slug].Indexed() {
+			xmlUrls = append(xmlUrls, slug)
+		}
 		return nil
 	})
 
 	if err != nil {
-		fmt.Println("Error walking directory:", err)
-		return
+		return fmt.Errorf("walking directory: %w", err)
+	}
+	trackRenames(site.Pages)
+	if drafts > 0 {
+		fmt.Printf("Skipped %d drafts (build with --drafts to include them)\n", drafts)
+	}
+	if scheduled > 0 {
+		fmt.Printf("Skipped %d pages scheduled for later (build with --future to include them)\n", scheduled)
+	}
+	if err := cache.Save(); err != nil {
+		fmt.Println("Error saving build cache:", err)
+	}
+	if err := locks.Save(); err != nil {
+		return err
+	}
+	static, err := CopyStaticDir(cfg.StaticDir)
+	if err != nil {
+		return fmt.Errorf("copying %s: %w", cfg.StaticDir, err)
+	}
+	if err := CopyAssets(assets); err != nil {
+		return fmt.Errorf("copying assets: %w", err)
+	}
+	if static+len(assets) > 0 {
+		fmt.Printf("Copied %d assets and %d static files\n", len(assets), static)
+	}
+	variants := ProcessImages(site.Pages, assets)
+	assetManifest := make(AssetManifest)
+	if err := assetManifest.Rename(append(assets, variants...), site.Pages); err != nil {
+		return fmt.Errorf("fingerprinting assets: %w", err)
+	}
+	if summarizer != nil {
+		if err := summarizer.Save(); err != nil {
+			fmt.Println("Error saving summary cache:", err)
+		}
+	}
+
+	glossaryPage := len(siteGlossary) > 0
+	if _, exists := site.Pages[GlossarySlug]; exists && glossaryPage {
+		fmt.Println("Warning: content defines", GlossarySlug, "- skipping generated glossary")
+		glossaryPage = false
+	}
+	if glossaryPage {
+		addMenuItem(menuRoot, []string{"glossary"}, GlossarySlug, "Glossary", 0)
+	}
+	sortMenu(menuRoot.Children)
+	applyDirMeta(menuRoot.Children, "", dirMetas)
+	site.Menu = menuRoot.Children
+
+	ComputeRelated(site.Pages)
+	site.Taxonomies = ComputeTaxonomies(site.Pages)
+	site.Tags = ListTerms(site.Pages, func(p PageData) []string { return p.Tags })
+	site.Categories = ListTerms(site.Pages, func(p PageData) []string {
+		if p.Category == "" {
+			return nil
+		}
+		return []string{p.Category}
+	})
+	for _, name := range sortedKeys(cfg.Taxonomies) {
+		if site.Terms == nil {
+			site.Terms = make(map[string]map[string]*TermListing)
+		}
+		site.Terms[name] = ListTerms(site.Pages, func(p PageData) []string { return p.Terms[name] })
+	}
+	stats := ComputeStats(site.Pages)
+	reportUnusedSnippets(snippets, usedSnippets)
+	whatsNew := cfg.WhatsNewDays > 0 || cfg.WhatsNewBuilds > 0
+	var history *ChangeHistory
+	if whatsNew {
+		history = LoadChangeHistory(cfg.ChangeHistoryFile)
+		if history != nil {
+			history.Record(site.Pages, buildTime)
+		}
+		if err := history.Save(); err != nil {
+			fmt.Println("Error saving change history:", err)
+		}
+	}
+
+	// Generated Pages
+	if glossaryPage {
+		site.Pages[GlossarySlug] = BuildGlossaryPage(termUsage, site.Pages)
+		xmlUrls = append(xmlUrls, GlossarySlug)
+	}
+
+	termPages := BuildTermPages(TagsSlug, "Tag", "Tags", site.Tags)
+	for slug, page := range BuildTermPages(CategoriesSlug, "Category", "Categories", site.Categories) {
+		termPages[slug] = page
+	}
+	for name, listings := range site.Terms {
+		title, label := taxonomyNames(name)
+		for slug, page := range BuildTermPages(taxonomySlug(name), label, title, listings) {
+			termPages[slug] = page
+		}
+	}
+	for _, slug := range sortedKeys(termPages) {
+		if _, exists := site.Pages[slug]; exists {
+			fmt.Println("Warning: content defines", slug, "- skipping generated taxonomy page")
+			continue
+		}
+		site.Pages[slug] = termPages[slug]
+		xmlUrls = append(xmlUrls, slug)
+	}
+
+	site.Posts = BuildPostList(site.Pages)
+	if site.Posts != nil {
+		listPages := BuildListPages(site.Posts, site.Pages)
+		for _, slug := range sortedKeys(listPages) {
+			if _, exists := site.Pages[slug]; !exists {
+				xmlUrls = append(xmlUrls, slug)
+			}
+			site.Pages[slug] = listPages[slug]
+		}
+	}
+
+	if cfg.StatsPage {
+		if _, exists := site.Pages[StatsSlug]; exists {
+			fmt.Println("Warning: content defines", StatsSlug, "- skipping generated stats page")
+		} else {
+			site.Pages[StatsSlug] = BuildStatsPage(stats)
+			xmlUrls = append(xmlUrls, StatsSlug)
+		}
+	}
+
+	if whatsNew {
+		if _, exists := site.Pages[WhatsNewSlug]; exists {
+			fmt.Println("Warning: content defines", WhatsNewSlug, "- skipping generated what's new page")
+		} else {
+			site.Pages[WhatsNewSlug] = BuildWhatsNewPage(site.Pages, history, buildTime)
+			xmlUrls = append(xmlUrls, WhatsNewSlug)
+		}
+	}
+
+	var catalog Catalog
+	if cfg.CatalogPage {
+		catalog = BuildCatalog(site.Pages)
+		if _, exists := site.Pages[CatalogSlug]; exists {
+			fmt.Println("Warning: content defines", CatalogSlug, "- skipping generated catalog page")
+		} else {
+			site.Pages[CatalogSlug] = BuildCatalogPage(catalog)
+			xmlUrls = append(xmlUrls, CatalogSlug)
+		}
+	}
+
+	navMenu, err := LoadNav(cfg.NavFile, site.Pages)
+	if err != nil {
+		return err
+	}
+	if navMenu != nil {
+		site.Menu = navMenu
+	}
+	if site.Menu, err = AddMenuEntries(site.Menu, cfg.Menu, site.Pages); err != nil {
+		return err
+	}
+	LinkNeighbours(site.Pages, site.Menu)
+	AddBreadcrumbs(site.Pages, site.Menu)
+
+	if _, exists := site.Pages[SiteIndexSlug]; exists {
+		fmt.Println("Warning: content defines", SiteIndexSlug, "- skipping generated site index")
+	} else {
+		site.Pages[SiteIndexSlug] = BuildSiteIndexPage(site.Menu, site.Pages)
+		xmlUrls = append(xmlUrls, SiteIndexSlug)
+	}
+
+	site.Redirects = CollectRedirects(site.Pages)
+	if hasShortLinks(site.Pages) {
+		if _, exists := site.Pages[ShortLinksSlug]; exists {
+			fmt.Println("Warning: content defines", ShortLinksSlug, "- skipping generated short links page")
+		} else {
+			site.Pages[ShortLinksSlug] = BuildShortLinksPage(site.Pages, site.Redirects)
+		}
+	}
+	if broken := ResolveTransclusions(&site) + ResolveWikiLinks(&site); broken > 0 && cfg.Strict {
+		return fmt.Errorf("%d broken links", broken)
+	}
+	if cfg.ReviewBadge {
+		AddReviewBadges(site.Pages, buildTime)
+	}
+	links := CollectLinks(&site)
+	ComputeBacklinks(site.Pages, links)
+	AddSectionListings(site.Pages, site.Menu, sections)
+	AddSocialMeta(site.Pages, assetManifest)
+	AddStructuredData(site.Pages)
+	if cfg.QRCodes || cfg.QRCommand != "" {
+		fmt.Printf("Generated %d QR codes\n", WriteQRCodes(site.Pages))
 	}
 
 	// Output Generation
-	if err := GenerateXMLSitemap(xmlUrls); err != nil {
+	if err := GenerateXMLSitemap(xmlUrls, site.Pages); err != nil {
 		fmt.Println("Error generating sitemap:", err)
 	}
+	if cfg.NewsSitemap {
+		if err := GenerateNewsSitemap(site.Pages); err != nil {
+			fmt.Println("Error generating news sitemap:", err)
+		}
+	}
 
-	jsonBytes, _ := json.Marshal(site)
-	if err := os.WriteFile(filepath.Join(OutputDir, "db.json"), jsonBytes, 0644); err != nil {
+	if err := WriteRedirects(site.Redirects); err != nil {
+		fmt.Println("Error writing redirects:", err)
+	}
+	if err := WriteRewrites(site.Redirects); err != nil {
+		fmt.Println("Error writing host rewrites:", err)
+	}
+
+	feeds := SiteFeeds(site.Pages)
+	if err := WriteFeeds(feeds); err != nil {
+		fmt.Println("Error writing feeds:", err)
+	}
+
+	if err := WriteStats(stats); err != nil {
+		fmt.Println("Error writing stats.json:", err)
+	}
+	if review := ReviewReport(site.Pages, buildTime); len(review) > 0 {
+		if err := WriteReviewReport(review); err != nil {
+			fmt.Println("Error writing review.json:", err)
+		}
+	}
+	if cfg.StaleMonths > 0 || len(cfg.StaleSections) > 0 {
+		if err := WriteStaleReport(StaleReport(site.Pages, buildTime)); err != nil {
+			fmt.Println("Error writing stale.json:", err)
+		}
+	}
+
+	if err := WriteSearchIndex(BuildSearchIndex(site.Pages), assetManifest); err != nil {
+		fmt.Println("Error writing search.json:", err)
+	}
+	if err := WritePalette(BuildPalette(site.Pages), assetManifest); err != nil {
+		fmt.Println("Error writing palette.json:", err)
+	}
+	if err := WriteLinkGraph(BuildLinkGraph(site.Pages, links), assetManifest); err != nil {
+		fmt.Println("Error writing graph.json:", err)
+	}
+	if cfg.CatalogPage {
+		if err := WriteCatalog(catalog, assetManifest); err != nil {
+			fmt.Println("Error writing catalog.json:", err)
+		}
+	}
+	if err := WritePageMeta(site.Pages); err != nil {
+		fmt.Println("Error writing page metadata:", err)
+	}
+
+	for slug, page := range site.Pages {
+		page.Content = minifyHTML(page.Content)
+		site.Pages[slug] = page
+	}
+	site.Version = cfg.APIVersion
+	db := site
+	if cfg.APIVersion >= 2 {
+		if db.Pages, err = WritePageContent(site.Pages); err != nil {
+			fmt.Println("Error writing page content:", err)
+		}
+	}
+	jsonBytes, _ := json.Marshal(db)
+	if cfg.Pretty {
+		jsonBytes, _ = json.MarshalIndent(db, "", "  ")
+	}
+	if err := assetManifest.WriteFile("db.json", jsonBytes); err != nil {
 		fmt.Println("Error writing db.json:", err)
 	}
+	if err := assetManifest.Save(); err != nil {
+		fmt.Println("Error writing", AssetManifestFile+":", err)
+	}
+	schema := SiteSchema()
+	if err := WriteSchema(schema); err != nil {
+		fmt.Println("Error writing", SchemaFile+":", err)
+	}
+	if cfg.ValidateOutput {
+		if err := ValidateOutput(schema, assetManifest.Name("db.json")); err != nil {
+			return err
+		}
+		fmt.Println("db.json matches", SchemaFile)
+	}
 
-	if err := WriteAppShell(filepath.Join(OutputDir, "index.html")); err != nil {
-		fmt.Println("Error writing index.html:", err)
+	if cfg.LitePages {
+		if err := WriteLitePages(site.Pages); err != nil {
+			fmt.Println("Error writing lite pages:", err)
+		}
+	}
+
+	partials, err := LoadPartials(cfg.PartialsDir)
+	if err != nil {
+		return fmt.Errorf("loading partials: %w", err)
+	}
+	if cfg.Profile == ProfileHTML {
+		if err := WriteHTMLSite(site, partials, feeds); err != nil {
+			fmt.Println("Error writing pages:", err)
+		}
+	} else {
+		if err := WritePrintStyle(assetManifest); err != nil {
+			fmt.Println("Error writing", PrintStyleFile+":", err)
+		}
+		shell, err := RenderAppShell(partials, feeds, assetManifest)
+		if err != nil {
+			return fmt.Errorf("rendering the app shell: %w", err)
+		}
+		if err := WriteAppShell(filepath.Join(cfg.OutputDir, "index.html"), shell); err != nil {
+			fmt.Println("Error writing index.html:", err)
+		}
+		if cfg.Prerender {
+			if err := WritePrerendered(shell, site.Pages, feeds); err != nil {
+				fmt.Println("Error prerendering pages:", err)
+			}
+		}
+	}
+
+	if cfg.Precompress {
+		count, err := Precompress()
+		if err != nil {
+			fmt.Println("Error precompressing output:", err)
+		}
+		fmt.Printf("Precompressed %d files\n", count)
 	}
 
 	fmt.Println("--- DONE ---")
+	return nil
 }
 
-// Logic for building the nested menu structure
-func addMenuItem(nodes []*MenuItem, parts []string, slug, finalTitle string, weight int) []*MenuItem {
+// Logic for building the nested menu structure. Nodes are looked up by
+// path segment; call sortMenu once the tree is complete.
+func addMenuItem(parent *MenuItem, parts []string, slug, finalTitle string, weight int) {
 	if len(parts) == 0 {
-		return nodes
+		return
 	}
 	currentPart := parts[0]
 	isLast := len(parts) == 1
-	var foundNode *MenuItem
-
-	for _, node := range nodes {
-		if node.Title == strings.Title(strings.ReplaceAll(currentPart, "-", " ")) && node.IsFolder == !isLast {
-			foundNode = node
-			break
-		}
+	key := currentPart
+	if !isLast {
+		key += "/"
+	}
+	if parent.index == nil {
+		parent.index = make(map[string]*MenuItem)
 	}
 
-	if foundNode == nil {
-		title := strings.Title(strings.ReplaceAll(currentPart, "-", " "))
-		if isLast {
-			title = finalTitle
-		}
-
-		newNode := &MenuItem{Title: title, IsFolder: !isLast, Children: []*MenuItem{}}
+	node := parent.index[key]
+	if node == nil {
+		node = &MenuItem{Title: titleCase(currentPart), IsFolder: !isLast, Children: []*MenuItem{}, name: currentPart}
 		if isLast {
-			newNode.Slug = slug
-			newNode.Weight = weight
-		} else {
-			newNode.Weight = 0
+			node.Title = finalTitle
+			node.Slug = slug
+			node.Weight = weight
 		}
+		parent.index[key] = node
+		parent.Children = append(parent.Children, node)
+	}
 
-		nodes = append(nodes, newNode)
-		foundNode = newNode
-
-		sort.Slice(nodes, func(i, j int) bool {
-			if nodes[i].Slug == "/" {
-				return true
-			}
-			if nodes[j].Slug == "/" {
-				return false
-			}
-
-			if nodes[i].Weight != nodes[j].Weight {
-				return nodes[i].Weight < nodes[j].Weight
-			}
-			if nodes[i].IsFolder != nodes[j].IsFolder {
-				return nodes[i].IsFolder // Folders first
+	if !isLast {
+		// A folder's index.md is its landing page, reached by clicking the
+		// folder; an _index.md also names and orders the folder
+		if len(parts) == 2 && (parts[1] == "index" || parts[1] == SectionIndex) {
+			node.Slug = slug
+			if parts[1] == SectionIndex {
+				node.Title, node.Weight = finalTitle, weight
 			}
-			return nodes[i].Title < nodes[j].Title
-		})
+			return
+		}
+		addMenuItem(node, parts[1:], slug, finalTitle, weight)
 	}
+}
 
-	if !isLast {
-		foundNode.Children = addMenuItem(foundNode.Children, parts[1:], slug, finalTitle, weight)
+// sortMenu orders every level of the menu: Home first, then by weight,
+// folders before pages and finally by title.
+func sortMenu(nodes []*MenuItem) {
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].Slug == "/" {
+			return true
+		}
+		if nodes[j].Slug == "/" {
+			return false
+		}
+
+		if nodes[i].Weight != nodes[j].Weight {
+			return nodes[i].Weight < nodes[j].Weight
+		}
+		if nodes[i].IsFolder != nodes[j].IsFolder {
+			return nodes[i].IsFolder // Folders first
+		}
+		return nodes[i].Title < nodes[j].Title
+	})
+	for _, node := range nodes {
+		sortMenu(node.Children)
 	}
-	return nodes
-}
\ No newline at end of file
+}
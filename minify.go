@@ -0,0 +1,38 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Patterns of the HTML minifier: elements whose whitespace matters, runs of
+// whitespace, and whitespace in front of block-level tags, where browsers
+// ignore it anyway
+var (
+	preservedRegex = regexp.MustCompile(`(?is)<(?:pre|textarea|script|style)\b.*?</(?:pre|textarea|script|style)>`)
+	spaceRegex     = regexp.MustCompile(`\s+`)
+	blockGapRegex  = regexp.MustCompile(`(?i)> <(/?(?:address|article|aside|blockquote|body|details|div|dl|dd|dt|fieldset|figcaption|figure|footer|form|h[1-6]|head|header|hr|html|li|link|main|meta|nav|ol|p|picture|section|source|summary|table|tbody|td|tfoot|th|thead|title|tr|ul)\b)`)
+)
+
+// minifyHTML collapses whitespace in rendered HTML, leaving <pre>,
+// <textarea>, <script> and <style> elements alone. With cfg.Pretty the
+// HTML is returned as is.
+func minifyHTML(content string) string {
+	if cfg.Pretty {
+		return content
+	}
+	var buf strings.Builder
+	last := 0
+	for _, loc := range preservedRegex.FindAllStringIndex(content, -1) {
+		buf.WriteString(minifyText(content[last:loc[0]]))
+		buf.WriteString(content[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	buf.WriteString(minifyText(content[last:]))
+	return strings.TrimSpace(buf.String())
+}
+
+func minifyText(s string) string {
+	s = spaceRegex.ReplaceAllString(s, " ")
+	return blockGapRegex.ReplaceAllString(s, "><$1")
+}
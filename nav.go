@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// NavEntry is one item of nav.yaml: a page, an external link, a group
+// with children, or a separator.
+type NavEntry struct {
+	Title     string     `yaml:"title"`
+	Slug      string     `yaml:"slug"`
+	URL       string     `yaml:"url"`
+	Icon      string     `yaml:"icon"` // Lineicons name, e.g. github
+	Separator bool       `yaml:"separator"`
+	Expand    string     `yaml:"expand"`
+	Children  []NavEntry `yaml:"children"`
+
+	// Placement of the `menu` entries of the config in the folder tree:
+	// the folder (path of folder names, top level if empty) and the
+	// weight among its items
+	Parent string `yaml:"parent"`
+	Weight int    `yaml:"weight"`
+}
+
+// LoadNav builds the menu from the nav file, returning nil if there is none.
+// Every referenced slug must exist in pages.
+func LoadNav(path string, pages map[string]PageData) ([]*MenuItem, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []NavEntry
+	if err := yaml.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("invalid nav file %s: %w", path, err)
+	}
+	var problems []string
+	menu := buildNav(entries, pages, &problems)
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid nav file %s:\n  %s", path, strings.Join(problems, "\n  "))
+	}
+	return menu, nil
+}
+
+func buildNav(entries []NavEntry, pages map[string]PageData, problems *[]string) []*MenuItem {
+	items := []*MenuItem{}
+	for _, e := range entries {
+		switch {
+		case e.Separator:
+			items = append(items, &MenuItem{Separator: true})
+		case e.URL != "":
+			if e.Title == "" {
+				*problems = append(*problems, fmt.Sprintf("link %s has no title", e.URL))
+			}
+			items = append(items, &MenuItem{Title: e.Title, URL: e.URL, Icon: menuIcon(e.Icon)})
+		case e.Slug != "":
+			slug := normalizeSlug("/" + strings.Trim(e.Slug, "/"))
+			page, ok := pages[slug]
+			if !ok {
+				*problems = append(*problems, fmt.Sprintf("page %s does not exist", slug))
+				continue
+			}
+			title := e.Title
+			if title == "" {
+				title = page.MenuTitle
+			}
+			if title == "" {
+				title = page.Title
+			}
+			items = append(items, &MenuItem{Title: title, Slug: slug, Weight: page.Weight, Icon: menuIcon(e.Icon)})
+		case len(e.Children) > 0:
+			if e.Title == "" {
+				*problems = append(*problems, "group has no title")
+			}
+			if !validExpand(e.Expand) {
+				*problems = append(*problems, fmt.Sprintf("group %q has unknown expand state %q", e.Title, e.Expand))
+			}
+			items = append(items, &MenuItem{Title: e.Title, IsFolder: true, Expand: e.Expand, Icon: menuIcon(e.Icon), Children: buildNav(e.Children, pages, problems)})
+		default:
+			*problems = append(*problems, fmt.Sprintf("entry %q needs one of slug, url, children or separator", e.Title))
+		}
+	}
+	return items
+}
+
+// menuIcon returns the Lineicons name of an icon setting ("lni-github" or
+// "github")
+func menuIcon(icon string) string {
+	return strings.TrimPrefix(strings.TrimSpace(icon), "lni-")
+}
+
+// AddMenuEntries puts the `menu` entries of the config into the menu, each
+// in its parent folder after the items weighing no more than it
+func AddMenuEntries(menu []*MenuItem, entries []NavEntry, pages map[string]PageData) ([]*MenuItem, error) {
+	var problems []string
+	for _, e := range entries {
+		items := buildNav([]NavEntry{e}, pages, &problems)
+		if len(items) == 0 {
+			continue
+		}
+		item := items[0]
+		item.Weight = e.Weight
+		siblings := &menu
+		if e.Parent != "" {
+			folder := menuFolder(menu, strings.Split(strings.Trim(e.Parent, "/"), "/"))
+			if folder == nil {
+				problems = append(problems, fmt.Sprintf("entry %q: no menu folder %s", e.Title, e.Parent))
+				continue
+			}
+			siblings = &folder.Children
+		}
+		i := 0
+		for i < len(*siblings) && ((*siblings)[i].Slug == "/" || (*siblings)[i].Weight <= item.Weight) {
+			i++
+		}
+		*siblings = append((*siblings)[:i], append([]*MenuItem{item}, (*siblings)[i:]...)...)
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid menu entries in the config:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return menu, nil
+}
+
+// menuFolder finds the folder at path, matching folder names or titles
+func menuFolder(items []*MenuItem, path []string) *MenuItem {
+	for _, item := range items {
+		if item.IsFolder && (item.name == path[0] || strings.EqualFold(item.Title, path[0])) {
+			if len(path) == 1 {
+				return item
+			}
+			return menuFolder(item.Children, path[1:])
+		}
+	}
+	return nil
+}
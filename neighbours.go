@@ -0,0 +1,50 @@
+package main
+
+// LinkNeighbours sets the Prev and Next links of the pages in the menu to
+// the pages before and after them in their folder; posts link to the posts
+// published before and after them instead.
+func LinkNeighbours(pages map[string]PageData, menu []*MenuItem) {
+	var walk func(items []*MenuItem)
+	walk = func(items []*MenuItem) {
+		var folder []string
+		for _, item := range items {
+			if item.Slug != "" && item.URL == "" {
+				folder = append(folder, item.Slug)
+			}
+			walk(item.Children)
+		}
+		linkSequence(pages, folder)
+	}
+	walk(menu)
+
+	_, slugs := postSlugs(pages)
+	var posts []string
+	for i := len(slugs) - 1; i >= 0; i-- { // oldest first
+		if pages[slugs[i]].ListPage == 0 { // not a page of the post listing
+			posts = append(posts, slugs[i])
+		}
+	}
+	linkSequence(pages, posts)
+}
+
+// linkSequence links each page of slugs to the ones next to it, skipping
+// slugs without a page
+func linkSequence(pages map[string]PageData, slugs []string) {
+	var seq []string
+	for _, slug := range slugs {
+		if _, ok := pages[slug]; ok {
+			seq = append(seq, slug)
+		}
+	}
+	for i, slug := range seq {
+		page := pages[slug]
+		page.Prev, page.Next = nil, nil
+		if i > 0 {
+			page.Prev = &PageLink{Title: pages[seq[i-1]].Title, Slug: seq[i-1]}
+		}
+		if i < len(seq)-1 {
+			page.Next = &PageLink{Title: pages[seq[i+1]].Title, Slug: seq[i+1]}
+		}
+		pages[slug] = page
+	}
+}
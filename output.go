@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// build generates the site into a staging folder next to cfg.OutputDir and
+// swaps it in once complete, so a failed or interrupted build leaves the
+// previous output in place for whatever is serving it
+func build() error {
+	out := filepath.Clean(cfg.OutputDir)
+	staging, err := os.MkdirTemp(filepath.Dir(out), "."+filepath.Base(out)+"-build-")
+	if err != nil {
+		return fmt.Errorf("creating the staging folder: %w", err)
+	}
+	cfg.OutputDir = staging
+	err = generate()
+	cfg.OutputDir = out
+	if err == nil {
+		err = swapOutput(staging, out)
+	}
+	if err != nil {
+		os.RemoveAll(staging)
+	}
+	return err
+}
+
+// swapOutput replaces the output folder out with the finished build in
+// staging. When out is a symlink, a new link to staging replaces it in one
+// rename and the previous target is removed; otherwise the old folder is
+// moved aside and staging renamed in its place, leaving out missing for
+// no longer than between two renames, and never half written.
+func swapOutput(staging, out string) error {
+	if err := os.Chmod(staging, 0755); err != nil { // MkdirTemp creates it 0700
+		return err
+	}
+	if info, err := os.Lstat(out); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		previous, _ := filepath.EvalSymlinks(out)
+		target := staging
+		if dir := filepath.Dir(out); filepath.Dir(staging) == dir {
+			target = filepath.Base(staging) // keep the link relative, like the folder it sits in
+		}
+		link := staging + ".link"
+		if err := os.Symlink(target, link); err != nil {
+			return err
+		}
+		if err := os.Rename(link, out); err != nil {
+			os.Remove(link)
+			return err
+		}
+		if previous != "" {
+			os.RemoveAll(previous)
+		}
+		return nil
+	}
+
+	old := staging + ".old"
+	if err := os.Rename(out, old); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Rename(staging, out); err != nil {
+		os.Rename(old, out)
+		return err
+	}
+	return os.RemoveAll(old)
+}
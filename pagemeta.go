@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// PageMetaDir is the output folder of the per-page metadata and content files
+const PageMetaDir = "pages"
+
+// PageMeta is the metadata of one page without its rendered content,
+// written to pages/<slug>.meta.json for integrations
+type PageMeta struct {
+	Slug        string            `json:"slug"`
+	URL         string            `json:"url"`
+	Title       string            `json:"title"`
+	Description string            `json:"description"`
+	Published   string            `json:"published,omitempty"`
+	Updated     string            `json:"updated,omitempty"`
+	Category    string            `json:"category,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Keywords    []string          `json:"keywords,omitempty"`
+	TOC         []TOCEntry        `json:"toc"`
+	Related     []PageLink        `json:"related,omitempty"`
+	Meta        map[string]string `json:"meta,omitempty"`
+	NoIndex     bool              `json:"noindex,omitempty"`
+	Canonical   string            `json:"canonical,omitempty"`
+	Owners      []string          `json:"owners,omitempty"`
+	Type        string            `json:"type,omitempty"`
+	Extra       map[string]any    `json:"extra,omitempty"`
+	Data        interface{}       `json:"data,omitempty"`
+}
+
+// pageDataPath returns the output path of a per-page file with the given
+// extension, pages/index<ext> for the home page
+func pageDataPath(slug, ext string) string {
+	name := slug
+	if name == "/" {
+		name = "/index"
+	}
+	return filepath.Join(cfg.OutputDir, PageMetaDir, filepath.FromSlash(name)+ext)
+}
+
+// WritePageMeta writes the metadata file of every page
+func WritePageMeta(pages map[string]PageData) error {
+	for slug, page := range pages {
+		meta := PageMeta{
+			Slug:        slug,
+			URL:         pageURL(slug),
+			Title:       page.Title,
+			Description: page.Description,
+			Published:   page.Published,
+			Updated:     page.Updated,
+			Category:    page.Category,
+			Tags:        page.Tags,
+			Keywords:    page.Keywords,
+			TOC:         page.TOC,
+			Related:     page.Related,
+			Meta:        page.Meta,
+			NoIndex:     page.NoIndex,
+			Canonical:   page.Canonical,
+			Owners:      page.Owners,
+			Type:        page.Type,
+			Extra:       page.Extra,
+			Data:        page.Data,
+		}
+		if meta.TOC == nil {
+			meta.TOC = []TOCEntry{}
+		}
+		path := pageDataPath(slug, ".meta.json")
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		data, _ := json.MarshalIndent(meta, "", "  ")
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WritePageContent writes the content of every page to pages/<slug>.json
+// (db.json version 2) and returns the pages without it.
+func WritePageContent(pages map[string]PageData) (map[string]PageData, error) {
+	stripped := make(map[string]PageData, len(pages))
+	for slug, page := range pages {
+		path := pageDataPath(slug, ".json")
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return pages, err
+		}
+		data, _ := json.Marshal(map[string]string{"content": page.Content})
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return pages, err
+		}
+		page.Content = ""
+		stripped[slug] = page
+	}
+	return stripped, nil
+}
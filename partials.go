@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Injection points of the app shell, filled from <PartialsDir>/<name>.html
+var partialNames = []string{"before-head-end", "after-body-start", "sidebar-footer"}
+
+// LoadPartials reads the HTML partials for every injection point
+func LoadPartials(dir string) (map[string]string, error) {
+	partials := make(map[string]string)
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return partials, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".html")
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".html" {
+			continue
+		}
+		if !isPartialName(name) {
+			fmt.Printf("Warning: %s: unknown injection point, expected one of %s\n",
+				filepath.Join(dir, entry.Name()), strings.Join(partialNames, ", "))
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		partials[name] = string(data)
+	}
+	return partials, nil
+}
+
+func isPartialName(name string) bool {
+	for _, n := range partialNames {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
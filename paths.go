@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// Files are known by slash-separated paths relative to cfg.InputDir or
+// cfg.OutputDir everywhere but at the file system, so slugs, the build
+// cache, content locks and links come out the same on Windows and Unix.
+
+// slashPath turns a path from content into a slash-separated one; content
+// written on Windows may use backslashes
+func slashPath(p string) string {
+	return path.Clean(strings.ReplaceAll(p, `\`, "/"))
+}
+
+// contentRel returns the slash-separated path of file, a path under
+// cfg.InputDir as found by walking it
+func contentRel(file string) string {
+	rel, err := filepath.Rel(cfg.InputDir, file)
+	if err != nil {
+		return filepath.ToSlash(file)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// contentFile returns the file at the slash-separated path rel under
+// cfg.InputDir
+func contentFile(rel string) string {
+	return filepath.Join(cfg.InputDir, filepath.FromSlash(rel))
+}
+
+// outputFile returns the file at the slash-separated path rel under
+// cfg.OutputDir
+func outputFile(rel string) string {
+	return filepath.Join(cfg.OutputDir, filepath.FromSlash(rel))
+}
+
+// pageSlug returns the slug of the markdown file at the slash-separated
+// path rel: the path without its extension, or the folder for index.md and
+// _index.md pages
+func pageSlug(rel string) string {
+	dir, name := path.Split(strings.TrimSuffix(rel, ".md"))
+	if name == "index" || name == SectionIndex {
+		return "/" + strings.TrimSuffix(dir, "/")
+	}
+	return "/" + dir + name
+}
+
+// readMarkdown reads a markdown file of the content, returning a warning
+// for files over cfg.WarnFileKB. Files over cfg.MaxFileKB are not read and
+// give a nil source, as do binary files misnamed .md.
+func readMarkdown(file string) ([]byte, string) {
+	info, err := os.Stat(file)
+	if err != nil {
+		return nil, err.Error()
+	}
+	kb := (info.Size() + 1023) / 1024
+	if cfg.MaxFileKB > 0 && kb > int64(cfg.MaxFileKB) {
+		return nil, fmt.Sprintf("%d KB is over max_file_kb (%d KB), skipping it", kb, cfg.MaxFileKB)
+	}
+	source, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err.Error()
+	}
+	if !isText(source) {
+		return nil, "not a UTF-8 text file, skipping it"
+	}
+	if cfg.WarnFileKB > 0 && kb > int64(cfg.WarnFileKB) {
+		return source, fmt.Sprintf("%d KB is over warn_file_kb (%d KB)", kb, cfg.WarnFileKB)
+	}
+	return source, ""
+}
+
+// isText reports whether the start of data looks like UTF-8 text: valid and
+// without NUL bytes
+func isText(data []byte) bool {
+	head := data
+	if len(head) > 8000 {
+		head = head[:8000]
+		for len(head) > 0 && !utf8.RuneStart(data[len(head)]) {
+			head = head[:len(head)-1] // don't cut a character in two
+		}
+	}
+	return bytes.IndexByte(head, 0) < 0 && utf8.Valid(head)
+}
+
+// diskCase returns rel, an existing path under cfg.InputDir, with the case
+// of the names on disk. Case-insensitive file systems (Windows, macOS) find
+// ./Logo.PNG for logo.png, which breaks once deployed to a case-sensitive
+// server; links use the published name instead.
+func diskCase(rel string) string {
+	dir := cfg.InputDir
+	names := strings.Split(rel, "/")
+	for i, name := range names {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return rel
+		}
+		match := name
+		for _, e := range entries {
+			if e.Name() == name {
+				match = name
+				break
+			}
+			if strings.EqualFold(e.Name(), name) {
+				match = e.Name()
+			}
+		}
+		names[i] = match
+		dir = filepath.Join(dir, match)
+	}
+	return strings.Join(names, "/")
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// withDirs points cfg.InputDir and cfg.OutputDir at in and out for the
+// duration of the test
+func withDirs(t *testing.T, in, out string) {
+	t.Helper()
+	oldIn, oldOut := cfg.InputDir, cfg.OutputDir
+	cfg.InputDir, cfg.OutputDir = in, out
+	t.Cleanup(func() { cfg.InputDir, cfg.OutputDir = oldIn, oldOut })
+}
+
+func TestSlashPath(t *testing.T) {
+	tests := map[string]string{
+		"docs/setup.md":         "docs/setup.md",
+		`docs\setup.md`:         "docs/setup.md",
+		`docs\guides\..\faq.md`: "docs/faq.md",
+		"docs/./guides/../a.md": "docs/a.md",
+		`..\shared\note.md`:     "../shared/note.md",
+		`docs\\nested\\deep.md`: "docs/nested/deep.md",
+		"docs/":                 "docs",
+	}
+	for in, want := range tests {
+		if got := slashPath(in); got != want {
+			t.Errorf("slashPath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestContentPathsRoundTrip(t *testing.T) {
+	root := t.TempDir()
+	withDirs(t, filepath.Join(root, "content"), filepath.Join(root, "public"))
+
+	for _, rel := range []string{"index.md", "docs/setup.md", "docs/a b/_index.md", "vps/images/cover.png"} {
+		file := contentFile(rel)
+		if !strings.HasPrefix(file, cfg.InputDir+string(filepath.Separator)) {
+			t.Errorf("contentFile(%q) = %q, not under %q", rel, file, cfg.InputDir)
+		}
+		if got := contentRel(file); got != rel {
+			t.Errorf("contentRel(contentFile(%q)) = %q", rel, got)
+		}
+		out := outputFile(rel)
+		if got, err := filepath.Rel(cfg.OutputDir, out); err != nil || filepath.ToSlash(got) != rel {
+			t.Errorf("outputFile(%q) = %q, not %q under %q", rel, out, rel, cfg.OutputDir)
+		}
+	}
+}
+
+func TestPageSlug(t *testing.T) {
+	tests := map[string]string{
+		"index.md":              "/",
+		"_index.md":             "/",
+		"about.md":              "/about",
+		"docs/index.md":         "/docs",
+		"docs/_index.md":        "/docs",
+		"docs/setup.md":         "/docs/setup",
+		"docs/guides/index.md":  "/docs/guides",
+		"docs/guides/_index.md": "/docs/guides",
+		"docs/guides/deploy.md": "/docs/guides/deploy",
+		"docs/indexing.md":      "/docs/indexing",
+	}
+	for rel, want := range tests {
+		if got := pageSlug(rel); got != want {
+			t.Errorf("pageSlug(%q) = %q, want %q", rel, got, want)
+		}
+	}
+}
+
+func TestDiskCase(t *testing.T) {
+	root := t.TempDir()
+	withDirs(t, root, filepath.Join(root, "public"))
+	if err := os.MkdirAll(filepath.Join(root, "Docs", "Images"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"Docs/Images/Logo.PNG", "Docs/readme.md"} {
+		if err := os.WriteFile(filepath.Join(root, filepath.FromSlash(name)), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := map[string]string{
+		"docs/images/logo.png": "Docs/Images/Logo.PNG",
+		"Docs/Images/Logo.PNG": "Docs/Images/Logo.PNG",
+		"DOCS/README.MD":       "Docs/readme.md",
+		"docs/missing.png":     "Docs/missing.png",
+	}
+	for rel, want := range tests {
+		if got := diskCase(rel); got != want {
+			t.Errorf("diskCase(%q) = %q, want %q", rel, got, want)
+		}
+	}
+}
+
+func TestIsText(t *testing.T) {
+	// a two-byte character across the 8000-byte mark must not be cut in two
+	straddling := []byte(strings.Repeat("a", 7999) + "é" + strings.Repeat("b", 100))
+	if !isText(straddling) {
+		t.Error("isText rejects text with a character across the 8000th byte")
+	}
+	// a four-byte character ending exactly at the mark
+	ending := []byte(strings.Repeat("a", 7996) + "😀" + "b")
+	if !isText(ending) {
+		t.Error("isText rejects text with a character ending at the 8000th byte")
+	}
+	if isText([]byte("text\x00with a NUL")) {
+		t.Error("isText accepts data with a NUL byte")
+	}
+	if isText([]byte{'a', 0xff, 0xfe, 'b'}) {
+		t.Error("isText accepts invalid UTF-8")
+	}
+	if !isText(append([]byte(strings.Repeat("a", 8000)), 0xff)) {
+		t.Error("isText looks past the first 8000 bytes")
+	}
+}
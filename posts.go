@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PostList is the paginated listing of the posts section. Listing page n
+// lives at Slug (n = 1) or Slug/page/n.
+type PostList struct {
+	Slug    string       `json:"slug"`
+	PerPage int          `json:"per_page"`
+	Total   int          `json:"total"`
+	Pages   [][]TermPage `json:"pages"`
+}
+
+// listPageSlug returns the route of listing page n
+func listPageSlug(base string, n int) string {
+	if n == 1 {
+		return base
+	}
+	return fmt.Sprintf("%s/page/%d", base, n)
+}
+
+// BuildPostList collects the pages under cfg.PostsSection, newest first,
+// split into pages of cfg.PostsPerPage. It returns nil when the section
+// is not set or empty.
+func BuildPostList(pages map[string]PageData) *PostList {
+	base, slugs := postSlugs(pages)
+	if len(slugs) == 0 {
+		return nil
+	}
+
+	list := &PostList{Slug: base, PerPage: cfg.PostsPerPage, Total: len(slugs)}
+	for start := 0; start < len(slugs); start += cfg.PostsPerPage {
+		end := min(start+cfg.PostsPerPage, len(slugs))
+		var items []TermPage
+		for _, slug := range slugs[start:end] {
+			page := pages[slug]
+			items = append(items, TermPage{Slug: slug, Title: page.Title, Published: page.Published, Description: page.Description})
+		}
+		list.Pages = append(list.Pages, items)
+	}
+	return list
+}
+
+// postSlugs returns the route of the posts section and its pages, newest
+// first; both are empty when the section is not set
+func postSlugs(pages map[string]PageData) (string, []string) {
+	if cfg.PostsSection == "" {
+		return "", nil
+	}
+	base := normalizeSlug("/" + strings.Trim(cfg.PostsSection, "/"))
+	var slugs []string
+	for slug := range pages {
+		if strings.HasPrefix(slug, base+"/") {
+			slugs = append(slugs, slug)
+		}
+	}
+	sort.Slice(slugs, func(i, j int) bool {
+		a, b := pages[slugs[i]], pages[slugs[j]]
+		if !a.PublishedAt.Equal(b.PublishedAt) {
+			return a.PublishedAt.After(b.PublishedAt)
+		}
+		return slugs[i] < slugs[j]
+	})
+	return base, slugs
+}
+
+// BuildListPages returns the pages showing the listing: the section's own
+// index page (generated when the content has none) and one per further
+// listing page.
+func BuildListPages(list *PostList, pages map[string]PageData) map[string]PageData {
+	generated := make(map[string]PageData)
+	title := titleCase(strings.TrimPrefix(list.Slug, "/"))
+	for n := 1; n <= len(list.Pages); n++ {
+		slug := listPageSlug(list.Slug, n)
+		page, ok := pages[slug]
+		if !ok {
+			page = PageData{Title: title, HideTOC: true, HideMeta: true}
+			if n > 1 {
+				page.Title = fmt.Sprintf("%s - Page %d", title, n)
+			}
+		}
+		page.ListPage = n
+		generated[slug] = page
+	}
+	return generated
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Markers in the app shell filled with the head tags and content of a page
+const (
+	prerenderHeadMarker = "<!--prerender:head-->"
+	prerenderBodyMarker = "<!--prerender:body-->"
+)
+
+// prerenderStyle shows the static copy only to readers without JavaScript
+// and in the print view (?print); everyone else gets the app, which replaces
+// the page tags on navigation.
+const prerenderStyle = `    <script>document.documentElement.classList.add(/[?&]print\b/.test(location.search) ? 'print-view' : 'js');</script>
+    <style>
+        .js #prerendered { display: none; }
+        html:not(.js) #app { display: none; }
+        .print-only { display: none; }
+    </style>
+`
+
+// pageHeadTags returns the canonical link and <meta> tags of a page, marked
+// so the app replaces them when navigating.
+func pageHeadTags(slug string, page *PageData) string {
+	var head strings.Builder
+	head.WriteString(fmt.Sprintf("    <link rel=\"canonical\" href=\"%s\" data-page-meta>\n", html.EscapeString(canonicalURL(slug, *page))))
+	if cfg.LitePages {
+		head.WriteString(fmt.Sprintf("    <link rel=\"alternate\" href=\"%s\" title=\"Lite version\" data-page-meta>\n", html.EscapeString(liteURL(slug))))
+	}
+	meta := make(map[string]string, len(page.Meta)+1)
+	for k, v := range page.Meta {
+		meta[k] = v
+	}
+	if page.NoIndex {
+		meta["robots"] = "noindex"
+	}
+	for _, key := range sortedKeys(meta) {
+		attr := "name"
+		if strings.HasPrefix(key, "og:") {
+			attr = "property"
+		}
+		head.WriteString(fmt.Sprintf("    <meta %s=\"%s\" content=\"%s\" data-page-meta>\n", attr, html.EscapeString(key), html.EscapeString(meta[key])))
+	}
+	if page.JSONLD != "" {
+		head.WriteString("    <script type=\"application/ld+json\" data-page-meta>" + page.JSONLD + "</script>\n")
+	}
+	return head.String()
+}
+
+// PageNav is the navigation around a prerendered page, executed with the
+// breadcrumbs.html and page-nav.html templates
+type PageNav struct {
+	Breadcrumbs []NavLink // PageData.Breadcrumbs
+	Prev, Next  *NavLink  // PageData.Prev and Next, as in the app
+	EditURL     string
+}
+
+// NavLink is a link of PageNav; Href is empty for folders without a page
+type NavLink struct {
+	Title string
+	Href  string
+}
+
+// pageNav collects the navigation of a page
+func pageNav(page PageData) PageNav {
+	nav := PageNav{EditURL: page.EditURL}
+	for _, link := range page.Breadcrumbs {
+		crumb := NavLink{Title: link.Title}
+		if link.Slug != "" {
+			crumb.Href = pageHref(link.Slug)
+		}
+		nav.Breadcrumbs = append(nav.Breadcrumbs, crumb)
+	}
+	if page.Prev != nil {
+		nav.Prev = &NavLink{Title: page.Prev.Title, Href: pageHref(page.Prev.Slug)}
+	}
+	if page.Next != nil {
+		nav.Next = &NavLink{Title: page.Next.Title, Href: pageHref(page.Next.Slug)}
+	}
+	return nav
+}
+
+// executeTemplate runs the named shell template into a string
+func executeTemplate(tmpl *template.Template, name string, data interface{}) (string, error) {
+	var buf bytes.Buffer
+	err := tmpl.ExecuteTemplate(&buf, name, data)
+	return buf.String(), err
+}
+
+// prerenderPage fills the prerender markers of the shell with the page at
+// slug and its navigation; a nil page leaves them empty.
+func prerenderPage(shell, slug string, page *PageData, nav *prerenderedNav, feeds []Feed) string {
+	if page == nil {
+		return strings.NewReplacer(prerenderHeadMarker+"\n", "", prerenderBodyMarker+"\n", "").Replace(shell)
+	}
+
+	var head strings.Builder
+	head.WriteString(prerenderStyle)
+	if cfg.URLRouting == RoutingHash && slug != "/" {
+		// The app only runs from the site root
+		head.WriteString(fmt.Sprintf("    <script>if (!location.hash && document.documentElement.classList.contains('js')) location.replace(%q);</script>\n", basePath()+"/#"+styledPath(slug)))
+	}
+	head.WriteString(pageHeadTags(slug, page))
+	if own := headFeeds(feeds, slug, *page); len(own) > 1 {
+		head.WriteString(feedLinks(own[1:])) // the shell links to the site feed
+	}
+
+	var body strings.Builder
+	body.WriteString("    <div id=\"prerendered\" class=\"w-full h-full overflow-y-auto p-8 lg:p-12\">\n")
+	body.WriteString("        <main class=\"mx-auto max-w-[var(--content-width)]\">\n")
+	body.WriteString(fmt.Sprintf("            <p><a href=\"%s\">%s</a></p>\n", pageHref("/"), html.EscapeString(cfg.Title)))
+	if nav != nil {
+		body.WriteString(nav.Breadcrumbs)
+	}
+	body.WriteString(fmt.Sprintf("            <h1 class=\"text-4xl font-bold mb-4\">%s</h1>\n", html.EscapeString(page.Title)))
+	if page.Published != "" && !page.HideMeta {
+		body.WriteString(fmt.Sprintf("            <p class=\"text-sm text-gray-500\">%s</p>\n", pageDates(*page)))
+	}
+	body.WriteString(printQRCode(page))
+	body.WriteString(printContents(page))
+	article, links := footnoteLinks(page.Content)
+	body.WriteString("            <article class=\"prose prose-slate prose-lg max-w-none\">\n")
+	body.WriteString(article)
+	body.WriteString("\n            </article>\n")
+	body.WriteString(links)
+	if nav != nil {
+		body.WriteString(nav.Footer)
+	}
+	body.WriteString("        </main>\n")
+	body.WriteString("    </div>\n")
+	body.WriteString(printViewScript)
+	content := body.String()
+	if cfg.URLRouting == RoutingHash {
+		// Crawlers follow links to the static copies instead of the app routes
+		content = strings.ReplaceAll(content, "href=\"#/", "href=\""+basePath()+"/")
+	}
+
+	description := page.Description
+	if description == "" {
+		description = cfg.Description
+	}
+	return strings.NewReplacer(
+		"<title>"+html.EscapeString(cfg.Title)+"</title>", "<title>"+html.EscapeString(page.Title+" - "+cfg.Title)+"</title>",
+		"<meta name=\"description\" content=\""+html.EscapeString(cfg.Description)+"\">", "<meta name=\"description\" content=\""+html.EscapeString(description)+"\">",
+		prerenderHeadMarker+"\n", head.String(),
+		prerenderBodyMarker+"\n", content,
+	).Replace(shell)
+}
+
+// prerenderedNav is the rendered PageNav of a page
+type prerenderedNav struct {
+	Breadcrumbs, Footer string
+}
+
+// WritePrerendered writes every page as <slug>/index.html with its
+// breadcrumbs and prev/next links; the home page replaces the plain shell
+// at index.html.
+func WritePrerendered(shell string, pages map[string]PageData, feeds []Feed) error {
+	tmpl, err := LoadShellTemplates(cfg.TemplatesDir)
+	if err != nil {
+		return err
+	}
+	for slug, page := range pages {
+		path := filepath.Join(cfg.OutputDir, filepath.FromSlash(slug), "index.html")
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		var nav *prerenderedNav
+		if page.Layout != LayoutLanding && page.Layout != LayoutBare {
+			data := pageNav(page)
+			nav = &prerenderedNav{}
+			if nav.Breadcrumbs, err = executeTemplate(tmpl, "breadcrumbs.html", data); err != nil {
+				return err
+			}
+			if nav.Footer, err = executeTemplate(tmpl, "page-nav.html", data); err != nil {
+				return err
+			}
+		}
+		if err := os.WriteFile(path, []byte(minifyHTML(prerenderPage(shell, slug, &page, nav, feeds))), 0644); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Prerendered %d pages\n", len(pages))
+	return nil
+}
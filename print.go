@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// PrintStyleFile is the print stylesheet of the app shell. Prerendered pages
+// opened with ?print apply it on screen as well: a print view with the
+// table of contents expanded and the URLs of links as footnotes.
+const PrintStyleFile = "print.css"
+
+// printLinkRegex matches a link of rendered HTML and its href
+var printLinkRegex = regexp.MustCompile(`(?s)<a\s[^>]*?href="([^"]*)"[^>]*>.*?</a>`)
+
+// printStyle drops the app chrome, lets the page flow over several sheets and
+// wraps long code lines instead of cutting them off
+const printStyle = `html, body, #app div, #app main, #prerendered { height: auto !important; overflow: visible !important; }
+body { display: block !important; background: #fff !important; color: #000 !important; font-size: 11pt; }
+#app aside, #app header, #app footer, #app button, #prerendered nav[aria-label="Pages"], .print-hide { display: none !important; }
+.print-only { display: revert !important; }
+pre, pre code { white-space: pre-wrap !important; overflow-wrap: anywhere; overflow: visible !important; }
+pre, blockquote, table, figure, img, .admonition { break-inside: avoid; }
+h1, h2, h3, h4 { break-after: avoid; }
+#app article a[href^="http"]::after { content: " (" attr(href) ")"; font-size: 0.85em; }
+.print-toc ol { list-style: none; padding-left: 1.25rem; }
+.print-links { font-size: 0.85em; overflow-wrap: anywhere; }
+.print-qr { float: right; margin: 0 0 1rem 1rem; }
+@page { margin: 2cm; }
+`
+
+// printViewScript turns a prerendered page opened with ?print into the
+// print view, where the print stylesheet applies on screen (the app is not
+// started, see prerenderStyle). Collapsed <details> open for printing.
+const printViewScript = `    <script>
+        if (document.documentElement.classList.contains('print-view')) {
+            document.getElementById('print-style').media = 'all';
+            document.querySelectorAll('details').forEach(d => d.open = true);
+        }
+        addEventListener('beforeprint', () => document.querySelectorAll('details').forEach(d => d.open = true));
+    </script>
+`
+
+// WritePrintStyle writes the print stylesheet to the output folder
+func WritePrintStyle(assets AssetManifest) error {
+	return assets.WriteFile(PrintStyleFile, []byte(printStyle))
+}
+
+// printContents lists the headings of a page for the print view; pages
+// without any get an empty string
+func printContents(page *PageData) string {
+	if len(page.TOC) == 0 || page.HideTOC {
+		return ""
+	}
+	var buf strings.Builder
+	buf.WriteString("            <nav class=\"print-only print-toc\" aria-label=\"Contents\">\n")
+	buf.WriteString("                <h2>Contents</h2>\n                <ol>\n")
+	for _, entry := range page.TOC {
+		title := html.EscapeString(entry.Title)
+		if entry.HTML != "" {
+			title = entry.HTML
+		}
+		buf.WriteString(fmt.Sprintf("                    <li style=\"margin-left: %drem\"><a href=\"#%s\">%s</a></li>\n", max(entry.Level-2, 0), html.EscapeString(entry.ID), title))
+	}
+	buf.WriteString("                </ol>\n            </nav>\n")
+	return buf.String()
+}
+
+// printQRCode shows the QR code of a page in the print view, for readers of
+// the paper copy to get back to it
+func printQRCode(page *PageData) string {
+	if page.QRCode == "" {
+		return ""
+	}
+	return fmt.Sprintf("            <img class=\"print-only print-qr\" src=\"%s\" alt=\"QR code of this page\" width=\"96\" height=\"96\">\n", html.EscapeString(page.QRCode))
+}
+
+// footnoteLinks numbers the links of content leading off the page, shown
+// only in print, and returns the list of their URLs to go below it. Links
+// to the same URL share a number.
+func footnoteLinks(content string) (string, string) {
+	var urls []string
+	numbers := make(map[string]int)
+	content = printLinkRegex.ReplaceAllStringFunc(content, func(link string) string {
+		href := html.UnescapeString(printLinkRegex.FindStringSubmatch(link)[1])
+		if strings.HasPrefix(href, "#/") {
+			href = basePath() + href[1:] // the static copy, as prerenderPage links it
+		}
+		if href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(href, "mailto:") {
+			return link
+		}
+		href = absoluteLink(href)
+		n, ok := numbers[href]
+		if !ok {
+			urls = append(urls, href)
+			n = len(urls)
+			numbers[href] = n
+		}
+		return fmt.Sprintf("%s<sup class=\"print-only\">[%d]</sup>", link, n)
+	})
+	if len(urls) == 0 {
+		return content, ""
+	}
+	var buf strings.Builder
+	buf.WriteString("            <ol class=\"print-only print-links\">\n")
+	for _, u := range urls {
+		buf.WriteString(fmt.Sprintf("                <li>%s</li>\n", html.EscapeString(u)))
+	}
+	buf.WriteString("            </ol>\n")
+	return content, buf.String()
+}
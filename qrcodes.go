@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// QRCodesDir is the folder of the output holding the QR codes of pages
+const QRCodesDir = "qr"
+
+// qrCodeFile returns the path of the QR code of the page at slug, relative
+// to the output folder: qr/index.png for the home page, qr/vps/setup.png
+func qrCodeFile(slug string) string {
+	if slug == "/" {
+		slug = "/index"
+	}
+	return QRCodesDir + slug + ".png"
+}
+
+// WriteQRCodes makes a PNG QR code of the canonical URL of every page, with
+// cfg.QRCommand when set, and links it from the page. Pages whose code fails are
+// reported and go without one. It returns the number of codes written.
+func WriteQRCodes(pages map[string]PageData) int {
+	count := 0
+	for _, slug := range sortedKeys(pages) {
+		page := pages[slug]
+		rel := qrCodeFile(slug)
+		out := outputFile(rel)
+		if err := os.MkdirAll(filepath.Dir(out), 0755); err != nil {
+			fmt.Printf("Warning: %s: %v\n", rel, err)
+			continue
+		}
+		if err := writeQRCode(canonicalURL(slug, page), out); err != nil {
+			fmt.Printf("Warning: QR code of %s: %v\n", slug, err)
+			continue
+		}
+		page.QRCode = path.Join(basePath()+"/", rel)
+		pages[slug] = page
+		count++
+	}
+	return count
+}
+
+// writeQRCode writes the QR code of url to the PNG file out
+func writeQRCode(url, out string) error {
+	if cfg.QRCommand != "" {
+		return runCommand(cfg.QRCommand, strings.NewReplacer("{url}", url, "{out}", out))
+	}
+	return qrcode.WriteFile(url, qrcode.Medium, cfg.QRSize, out)
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Redirect map formats, each written to its own file
+const (
+	RedirectsJSON    = "json"    // redirects.json
+	RedirectsNetlify = "netlify" // _redirects (also read by Cloudflare Pages)
+	RedirectsNginx   = "nginx"   // redirects.map, entries of an nginx map block
+	RedirectsCaddy   = "caddy"   // redirects.caddy, to import in a site block
+)
+
+var redirectFiles = map[string]string{
+	RedirectsJSON:    "redirects.json",
+	RedirectsNetlify: "_redirects",
+	RedirectsNginx:   "redirects.map",
+	RedirectsCaddy:   "redirects.caddy",
+}
+
+// redirectSlug turns an old path ("docs/Setup/") into a slug ("/docs/setup")
+func redirectSlug(path string) string {
+	return normalizeSlug("/" + strings.Trim(strings.TrimSpace(path), "/"))
+}
+
+// CollectRedirects builds the redirect map (old slug -> page slug) from
+// cfg.Redirects and the `aliases`/`previously`/`short` frontmatter of each page.
+// Chains are followed to their final page; entries shadowing a page or
+// conflicting with an earlier one are dropped with a warning.
+func CollectRedirects(pages map[string]PageData) map[string]string {
+	redirects := make(map[string]string)
+	add := func(from, to, source string) {
+		from, to = redirectSlug(from), redirectSlug(to)
+		if from == to {
+			return
+		}
+		if _, ok := pages[from]; ok {
+			fmt.Printf("Warning: %s: redirect from %s shadows an existing page, ignoring it\n", source, from)
+			return
+		}
+		if prev, ok := redirects[from]; ok && prev != to {
+			fmt.Printf("Warning: %s: %s already redirects to %s, ignoring redirect to %s\n", source, from, prev, to)
+			return
+		}
+		redirects[from] = to
+	}
+	for _, from := range sortedKeys(cfg.Redirects) {
+		add(from, cfg.Redirects[from], "config redirects")
+	}
+	for _, slug := range sortedKeys(pages) {
+		for _, alias := range pages[slug].Aliases {
+			add(alias, slug, slug)
+		}
+		if short := pages[slug].Short; short != "" {
+			add(short, slug, slug)
+		}
+	}
+	for _, from := range sortedKeys(watchRedirects) {
+		add(from, watchRedirects[from], "renamed page")
+	}
+
+	for _, from := range sortedKeys(redirects) {
+		to, ok := redirects[from]
+		if !ok {
+			continue // dropped as part of a loop
+		}
+		seen := map[string]bool{from: true}
+		for !seen[to] {
+			next, ok := redirects[to]
+			if !ok {
+				break
+			}
+			seen[to] = true
+			to = next
+		}
+		if seen[to] {
+			fmt.Printf("Warning: redirect loop through %s, ignoring it\n", from)
+			for slug := range seen {
+				delete(redirects, slug)
+			}
+			continue
+		}
+		redirects[from] = to
+		if _, ok := pages[to]; !ok {
+			fmt.Printf("Warning: %s redirects to %s, which is not a page\n", from, to)
+		}
+	}
+	return redirects
+}
+
+// redirectTarget returns the server path a redirect points to
+func redirectTarget(slug string) string {
+	if cfg.URLRouting == RoutingPath {
+		return basePath() + styledPath(slug)
+	}
+	return basePath() + "/#" + styledPath(slug)
+}
+
+// WriteRedirects writes the redirect map in every configured format
+func WriteRedirects(redirects map[string]string) error {
+	froms := sortedKeys(redirects)
+	for _, format := range cfg.RedirectFormats {
+		var buf strings.Builder
+		switch format {
+		case RedirectsJSON:
+			m := make(map[string]string, len(froms))
+			for _, from := range froms {
+				m[basePath()+from] = redirectTarget(redirects[from])
+			}
+			data, _ := json.MarshalIndent(m, "", "  ")
+			buf.Write(data)
+		case RedirectsNetlify:
+			for _, from := range froms {
+				buf.WriteString(fmt.Sprintf("%s %s 301\n", basePath()+from, redirectTarget(redirects[from])))
+			}
+		case RedirectsNginx:
+			buf.WriteString("# map $uri $redirect_target { include redirects.map; }\n")
+			buf.WriteString("# if ($redirect_target) { return 301 $redirect_target; }\n")
+			for _, from := range froms {
+				buf.WriteString(fmt.Sprintf("%s %s;\n", basePath()+from, redirectTarget(redirects[from])))
+			}
+		case RedirectsCaddy:
+			for _, from := range froms {
+				buf.WriteString(fmt.Sprintf("redir %s %s permanent\n", basePath()+from, redirectTarget(redirects[from])))
+			}
+		}
+		if err := os.WriteFile(filepath.Join(cfg.OutputDir, redirectFiles[format]), []byte(buf.String()), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Hosts that get rewrite rules sending deep links to the app shell
+const (
+	RewritesNetlify = "netlify" // appended to _redirects
+	RewritesVercel  = "vercel"  // vercel.json, with the redirect map
+	RewritesNginx   = "nginx"   // rewrites.nginx.conf, to include in a server block
+)
+
+// WriteRewrites writes the host rewrite rules needed for path routing:
+// every path without a file of its own is served the app shell.
+func WriteRewrites(redirects map[string]string) error {
+	if cfg.URLRouting != RoutingPath || cfg.Profile == ProfileHTML {
+		return nil
+	}
+	base := basePath()
+	for _, host := range cfg.HostRewrites {
+		switch host {
+		case RewritesNetlify:
+			f, err := os.OpenFile(filepath.Join(cfg.OutputDir, redirectFiles[RedirectsNetlify]), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(f, "%s/* %s/index.html 200\n", base, base)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				return err
+			}
+		case RewritesVercel:
+			type rule struct {
+				Source      string `json:"source"`
+				Destination string `json:"destination"`
+				Permanent   bool   `json:"permanent,omitempty"`
+			}
+			conf := struct {
+				Redirects []rule `json:"redirects,omitempty"`
+				Rewrites  []rule `json:"rewrites"`
+			}{Rewrites: []rule{{Source: base + "/(.*)", Destination: base + "/index.html"}}}
+			for _, from := range sortedKeys(redirects) {
+				conf.Redirects = append(conf.Redirects, rule{Source: base + from, Destination: redirectTarget(redirects[from]), Permanent: true})
+			}
+			data, _ := json.MarshalIndent(conf, "", "  ")
+			if err := os.WriteFile(filepath.Join(cfg.OutputDir, "vercel.json"), data, 0644); err != nil {
+				return err
+			}
+		case RewritesNginx:
+			conf := fmt.Sprintf("location %s/ {\n    try_files $uri $uri/ %s/index.html;\n}\n", base, base)
+			if err := os.WriteFile(filepath.Join(cfg.OutputDir, "rewrites.nginx.conf"), []byte(conf), 0644); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCollectRedirectsChains(t *testing.T) {
+	old := cfg.Redirects
+	t.Cleanup(func() { cfg.Redirects = old })
+	pages := map[string]PageData{
+		"/docs/setup": {Aliases: []string{"/install"}},
+		"/faq":        {},
+	}
+
+	tests := []struct {
+		name      string
+		redirects map[string]string
+		want      map[string]string
+	}{
+		{"direct", map[string]string{"/old": "/faq"}, map[string]string{"/old": "/faq"}},
+		{"chain", map[string]string{"/a": "/b", "/b": "/c", "/c": "/faq"},
+			map[string]string{"/a": "/faq", "/b": "/faq", "/c": "/faq"}},
+		{"chain through an alias", map[string]string{"/setup": "/install"},
+			map[string]string{"/setup": "/docs/setup"}},
+		{"normalized", map[string]string{"Old/Path/": "/A", " a ": "faq/"},
+			map[string]string{"/old/path": "/faq", "/a": "/faq"}},
+		{"loop", map[string]string{"/x": "/y", "/y": "/z", "/z": "/x", "/old": "/faq"},
+			map[string]string{"/old": "/faq"}},
+		{"self", map[string]string{"/faq/": "/faq"}, map[string]string{}},
+		{"shadowing a page", map[string]string{"/faq": "/docs/setup"}, map[string]string{}},
+		{"dangling", map[string]string{"/gone": "/nowhere"}, map[string]string{"/gone": "/nowhere"}},
+	}
+	for _, tt := range tests {
+		cfg.Redirects = tt.redirects
+		want := map[string]string{"/install": "/docs/setup"}
+		for from, to := range tt.want {
+			want[from] = to
+		}
+		if got := CollectRedirects(pages); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: CollectRedirects = %v, want %v", tt.name, got, want)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"html"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Similarity scores every pair of documents; result[i][j] is the similarity
+// of texts i and j. TF-IDF is built in, an embedding model can be plugged in
+// by replacing relatedSimilarity.
+type Similarity interface {
+	Compare(texts []string) [][]float64
+}
+
+var relatedSimilarity Similarity = tfidf{}
+
+var htmlTagRegex = regexp.MustCompile(`<[^>]*>`)
+
+// stopWords are skipped when building term vectors
+var stopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "are": true, "but": true, "not": true, "you": true,
+	"all": true, "any": true, "can": true, "has": true, "have": true, "this": true, "that": true,
+	"with": true, "from": true, "your": true, "will": true, "into": true, "then": true, "than": true,
+	"them": true, "they": true, "its": true, "was": true, "were": true, "been": true, "which": true,
+	"when": true, "what": true, "how": true, "use": true, "using": true, "also": true, "there": true,
+}
+
+// plainText strips tags from rendered HTML
+func plainText(content string) string {
+	return html.UnescapeString(htmlTagRegex.ReplaceAllString(content, " "))
+}
+
+// ComputeRelated fills PageData.Related with the RelatedCount most similar
+// pages, skipping pairs scoring below RelatedMinScore.
+func ComputeRelated(pages map[string]PageData) {
+	if cfg.RelatedCount <= 0 || len(pages) < 2 {
+		return
+	}
+	slugs := make([]string, 0, len(pages))
+	for slug := range pages {
+		slugs = append(slugs, slug)
+	}
+	sort.Strings(slugs)
+	texts := make([]string, len(slugs))
+	for i, slug := range slugs {
+		texts[i] = pages[slug].Title + " " + pages[slug].Description + " " + plainText(pages[slug].Content)
+	}
+
+	scores := relatedSimilarity.Compare(texts)
+	for i, slug := range slugs {
+		candidates := make([]int, 0, len(slugs))
+		for j := range slugs {
+			if j != i && scores[i][j] >= cfg.RelatedMinScore {
+				candidates = append(candidates, j)
+			}
+		}
+		sort.SliceStable(candidates, func(a, b int) bool {
+			return scores[i][candidates[a]] > scores[i][candidates[b]]
+		})
+		if len(candidates) > cfg.RelatedCount {
+			candidates = candidates[:cfg.RelatedCount]
+		}
+		page := pages[slug]
+		page.Related = nil
+		for _, j := range candidates {
+			page.Related = append(page.Related, PageLink{Title: pages[slugs[j]].Title, Slug: slugs[j]})
+		}
+		pages[slug] = page
+	}
+}
+
+// tfidf compares documents by cosine similarity of TF-IDF weighted terms
+type tfidf struct{}
+
+func (tfidf) Compare(texts []string) [][]float64 {
+	docs := make([]map[string]float64, len(texts))
+	df := make(map[string]int)
+	for i, text := range texts {
+		tf := make(map[string]float64)
+		for _, word := range tokenize(text) {
+			tf[word]++
+		}
+		for word := range tf {
+			df[word]++
+		}
+		docs[i] = tf
+	}
+
+	n := float64(len(texts))
+	for _, tf := range docs {
+		var norm float64
+		for word, count := range tf {
+			w := (1 + math.Log(count)) * math.Log(1+n/float64(df[word]))
+			tf[word] = w
+			norm += w * w
+		}
+		if norm == 0 {
+			continue
+		}
+		norm = math.Sqrt(norm)
+		for word := range tf {
+			tf[word] /= norm
+		}
+	}
+
+	scores := make([][]float64, len(texts))
+	for i := range scores {
+		scores[i] = make([]float64, len(texts))
+	}
+	for i := range docs {
+		for j := i + 1; j < len(docs); j++ {
+			a, b := docs[i], docs[j]
+			if len(b) < len(a) {
+				a, b = b, a
+			}
+			var dot float64
+			for word, w := range a {
+				dot += w * b[word]
+			}
+			scores[i][j], scores[j][i] = dot, dot
+		}
+	}
+	return scores
+}
+
+// tokenize lowercases text and splits it into words, dropping short words
+// and stop words
+func tokenize(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	out := words[:0]
+	for _, w := range words {
+		if len([]rune(w)) >= 3 && !stopWords[w] {
+			out = append(out, w)
+		}
+	}
+	return out
+}
@@ -2,9 +2,16 @@ package main
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"os"
 	"regexp"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
 
 	"github.com/yuin/goldmark"
 	highlighting "github.com/yuin/goldmark-highlighting/v2"
@@ -14,22 +21,30 @@ import (
 	"github.com/yuin/goldmark/parser"
 	"github.com/yuin/goldmark/renderer/html"
 	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
 )
 
 var (
 	wikiLinkRegex = regexp.MustCompile(`\[\[(.*?)(?:\|(.*?))?\]\]`)
 	refTagRegex   = regexp.MustCompile(`\{\{ref:(.*?)#(.*?)\}\}`)
+	defTagRegex   = regexp.MustCompile(`\{\{def:(.*?)\}\}`)
+	includeRegex  = regexp.MustCompile(`\{\{include:(.*?)\}\}`)
 	mdParser      goldmark.Markdown
 )
 
-func init() {
+// initMarkdown builds the markdown parser from the active configuration
+func initMarkdown() {
 	mdParser = goldmark.New(
 		goldmark.WithExtensions(
 			extension.GFM,
 			meta.New(meta.WithStoresInDocument()),
-			highlighting.NewHighlighting(highlighting.WithStyle("dracula")),
+			highlighting.NewHighlighting(highlighting.WithStyle(cfg.HighlightStyle)),
+			&externalLinks{Target: cfg.ExternalLinkTarget, Rel: cfg.ExternalLinkRel, Class: cfg.ExternalLinkClass},
+			&glossaryTerms{FirstOnly: cfg.GlossaryFirstOnly},
+			&containers{},
+			&alerts{},
 		),
-		goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+		goldmark.WithParserOptions(parser.WithAutoHeadingID(), parser.WithAttribute()),
 		goldmark.WithRendererOptions(html.WithHardWraps(), html.WithUnsafe()),
 	)
 }
@@ -40,15 +55,58 @@ type RenderResult struct {
 	Meta        map[string]interface{}
 	TOC         []TOCEntry
 	Description string
+	Terms       []string // glossary terms used on the page
+	Includes    []string // files pulled in via {{include:...}}, relative to cfg.InputDir
+	Refs        []string // slugs transcluded via {{ref:...}}
+	Warnings    []string
 }
 
+// errRenderStopped is returned by a render stopped by renderIsolated
+var errRenderStopped = errors.New("render stopped")
+
 // ProcessMarkdown takes raw bytes and returns processed HTML and metadata
 func ProcessMarkdown(source []byte) (*RenderResult, error) {
+	return processMarkdown(source, nil)
+}
+
+// processMarkdown is ProcessMarkdown giving up with errRenderStopped between
+// steps, and between headings of the TOC, once stop is closed. The markdown
+// parser and renderer themselves cannot be interrupted.
+func processMarkdown(source []byte, stop <-chan struct{}) (*RenderResult, error) {
+	stopped := func() bool {
+		select {
+		case <-stop:
+			return true
+		default:
+			return false
+		}
+	}
+
+	// 0. Resolve includes before parsing so snippets become part of the
+	// document, and run the shortcodes, whose HTML is put in after rendering
+	var includes, warnings []string
+	source = checkFrontmatter(source, &warnings)
+	source = expandIncludes(source, 0, &includes, &warnings)
+	var shortcodes shortcodeOutput
+	if stopped() {
+		return nil, errRenderStopped
+	}
+	source = shortcodes.expand(source, &warnings)
+	if stopped() {
+		return nil, errRenderStopped
+	}
+
 	context := parser.NewContext()
 	doc := mdParser.Parser().Parse(text.NewReader(source), parser.WithContext(context))
+	if stopped() {
+		return nil, errRenderStopped
+	}
 
 	// 1. Extract Metadata
-	metaData := meta.Get(context)
+	metaData, err := meta.TryGet(context)
+	if err != nil {
+		warnings = append(warnings, fmt.Sprintf("invalid frontmatter, ignoring it: %v", err))
+	}
 
 	// 2. Extract Description (first paragraph)
 	var description string
@@ -58,40 +116,49 @@ func ProcessMarkdown(source []byte) (*RenderResult, error) {
 		child := doc.FirstChild()
 		for child != nil {
 			if child.Kind() == ast.KindParagraph {
-				var buf bytes.Buffer
-				for c := child.FirstChild(); c != nil; c = c.NextSibling() {
-					if t, ok := c.(*ast.Text); ok {
-						buf.Write(t.Segment.Value(source))
-					}
-				}
-				description = string(buf.Bytes())
-				if len(description) > 160 {
-					description = description[:157] + "..."
-				}
+				description = truncateText(shortcodes.plain(inlineText(child, source)), cfg.DescriptionLength)
 				break
 			}
 			child = child.NextSibling()
 		}
 	}
 
-	// 3. Extract TOC
+	// 3. Extract TOC (explicit `{#id}` anchors may collide, auto IDs never do)
 	var toc []TOCEntry
-	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+	seenIDs := make(map[string]bool)
+	err = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
 		if !entering {
 			return ast.WalkContinue, nil
 		}
+		if stopped() {
+			return ast.WalkStop, errRenderStopped
+		}
 		if heading, ok := n.(*ast.Heading); ok {
 			idVal, found := heading.Attribute([]byte("id"))
 			if found {
-				toc = append(toc, TOCEntry{
-					Title: string(heading.Text(source)),
-					ID:    string(idVal.([]byte)),
+				id := string(idVal.([]byte))
+				if seenIDs[id] {
+					warnings = append(warnings, fmt.Sprintf("duplicate heading id %q", id))
+				}
+				seenIDs[id] = true
+				entry := TOCEntry{
+					Title: shortcodes.plain(inlineText(heading, source)),
+					ID:    id,
 					Level: heading.Level,
-				})
+				}
+				if cfg.TOCMarkup {
+					if markup := inlineMarkup(heading, source); markup != string(util.EscapeHTML([]byte(entry.Title))) {
+						entry.HTML = markup
+					}
+				}
+				toc = append(toc, entry)
 			}
 		}
 		return ast.WalkContinue, nil
 	})
+	if err != nil {
+		return nil, err
+	}
 
 	// 4. Render HTML
 	var buf bytes.Buffer
@@ -99,19 +166,292 @@ func ProcessMarkdown(source []byte) (*RenderResult, error) {
 		return nil, err
 	}
 	htmlContent := buf.String()
+	if stopped() {
+		return nil, errRenderStopped
+	}
+
+	// 5. Collect glossary usage, from tooltips and explicit {{def:term}} tags,
+	// and the refs; tags in code are shown as written, not expanded
+	terms, _ := context.Get(glossaryUsageKey).([]string)
+	outsideCode := codeRegex.ReplaceAllString(htmlContent, "")
+	for _, m := range defTagRegex.FindAllStringSubmatch(outsideCode, -1) {
+		term, _, ok := siteGlossary.lookup(strings.TrimSpace(m[1]))
+		if !ok {
+			warnings = append(warnings, fmt.Sprintf("unknown glossary term %q", m[1]))
+			continue
+		}
+		if !slices.Contains(terms, term) {
+			terms = append(terms, term)
+		}
+	}
+
+	var refs []string
+	for _, m := range refTagRegex.FindAllStringSubmatch(outsideCode, -1) {
+		slug := normalizeSlug("/" + strings.TrimPrefix(strings.TrimSpace(m[1]), "/"))
+		if !slices.Contains(refs, slug) {
+			refs = append(refs, slug)
+		}
+	}
 
-	// 5. Post-process Custom Syntax
+	// 6. Post-process Custom Syntax
 	htmlContent = processCustomSyntax(htmlContent)
+	if stopped() {
+		return nil, errRenderStopped
+	}
+	htmlContent = shortcodes.replace(htmlContent)
 
 	return &RenderResult{
 		HTML:        htmlContent,
 		Meta:        metaData,
 		TOC:         toc,
 		Description: description,
+		Terms:       terms,
+		Includes:    includes,
+		Refs:        refs,
+		Warnings:    warnings,
 	}, nil
 }
 
+// runningRenders tracks the renders of renderIsolated, abandonedRenders
+// the number of them given up on after a timeout. Until its next check for
+// being stopped, an abandoned render still reads mdParser, siteShortcodes and
+// siteGlossary, so a rebuild waits for it before reloading them.
+var (
+	runningRenders   sync.WaitGroup
+	abandonedRenders atomic.Int32
+)
+
+// renderIsolated runs ProcessMarkdown, turning a panic or a render taking
+// longer than cfg.RenderTimeout seconds into an error, so one pathological
+// file cannot crash or hang the build. A render that times out is told to
+// stop and its result dropped.
+func renderIsolated(source []byte) (*RenderResult, error) {
+	type outcome struct {
+		result *RenderResult
+		err    error
+	}
+	done := make(chan outcome, 1)
+	stop := make(chan struct{})
+	runningRenders.Add(1)
+	go func() {
+		defer runningRenders.Done()
+		defer func() {
+			if r := recover(); r != nil {
+				done <- outcome{err: fmt.Errorf("rendering crashed: %v", r)}
+			}
+		}()
+		result, err := processMarkdown(source, stop)
+		done <- outcome{result, err}
+	}()
+	var timeout <-chan time.Time
+	if cfg.RenderTimeout > 0 {
+		timeout = time.After(time.Duration(cfg.RenderTimeout) * time.Second)
+	}
+	select {
+	case o := <-done:
+		return o.result, o.err
+	case <-timeout:
+		close(stop)
+		abandonedRenders.Add(1)
+		return nil, fmt.Errorf("rendering took longer than render_timeout (%ds)", cfg.RenderTimeout)
+	}
+}
+
+// waitAbandonedRenders blocks until the renders that timed out have stopped.
+// One stuck inside the markdown parser holds up the rebuild until it returns.
+func waitAbandonedRenders() {
+	if abandonedRenders.Load() > 0 {
+		fmt.Println("Waiting for timed-out renders to stop...")
+	}
+	runningRenders.Wait()
+	abandonedRenders.Store(0)
+}
+
+// inlineText returns the plain text of a node's inline content, including
+// text inside emphasis, links and code spans.
+func inlineText(n ast.Node, source []byte) string {
+	var buf bytes.Buffer
+	ast.Walk(n, func(c ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch t := c.(type) {
+		case *ast.Text:
+			buf.Write(t.Segment.Value(source))
+			if t.SoftLineBreak() || t.HardLineBreak() {
+				buf.WriteByte(' ')
+			}
+		case *ast.String:
+			if t.IsCode() {
+				buf.WriteString(plainText(string(t.Value))) // raw markup from our transformers
+			} else {
+				buf.Write(t.Value)
+			}
+		case *ast.AutoLink:
+			buf.Write(t.Label(source))
+		case *ast.RawHTML, *ast.Image:
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	return strings.Join(strings.Fields(buf.String()), " ")
+}
+
+// inlineMarkup renders a node's inline content keeping only code and
+// emphasis tags; links are reduced to their text.
+func inlineMarkup(n ast.Node, source []byte) string {
+	var buf strings.Builder
+	ast.Walk(n, func(c ast.Node, entering bool) (ast.WalkStatus, error) {
+		switch t := c.(type) {
+		case *ast.Text:
+			if entering {
+				buf.WriteString(string(util.EscapeHTML(t.Segment.Value(source))))
+				if t.SoftLineBreak() || t.HardLineBreak() {
+					buf.WriteByte(' ')
+				}
+			}
+		case *ast.String:
+			if entering && !t.IsCode() {
+				buf.WriteString(string(util.EscapeHTML(t.Value)))
+			}
+		case *ast.CodeSpan:
+			if entering {
+				buf.WriteString("<code>")
+			} else {
+				buf.WriteString("</code>")
+			}
+		case *ast.Emphasis:
+			tag := "em"
+			if t.Level == 2 {
+				tag = "strong"
+			}
+			if entering {
+				buf.WriteString("<" + tag + ">")
+			} else {
+				buf.WriteString("</" + tag + ">")
+			}
+		case *ast.RawHTML, *ast.Image:
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	return strings.TrimSpace(buf.String())
+}
+
+// truncateText shortens s to at most max runes, cutting at a word boundary
+// and marking the cut with "...".
+func truncateText(s string, max int) string {
+	if max <= 0 || utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	runes := []rune(s)
+	cut := string(runes[:max-3])
+	if i := strings.LastIndex(cut, " "); i > len(cut)/2 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " ,;:.-") + "..."
+}
+
+// expandIncludes replaces {{include:path}} tags with the referenced file
+// (frontmatter stripped), recursing up to IncludeMaxDepth levels. Tags in
+// code are left as written.
+func expandIncludes(source []byte, depth int, includes, warnings *[]string) []byte {
+	return replaceOutsideCode(source, includeRegex, func(match []byte) []byte {
+		rel := slashPath(strings.TrimSpace(string(match[10 : len(match)-2])))
+		if depth >= cfg.IncludeMaxDepth {
+			*warnings = append(*warnings, fmt.Sprintf("include %s exceeds the nesting limit of %d", rel, cfg.IncludeMaxDepth))
+			return []byte(`<span class="text-red-500">[Include too deep: ` + rel + `]</span>`)
+		}
+		data, err := os.ReadFile(contentFile(rel))
+		if os.IsNotExist(err) && !strings.HasPrefix(rel, SnippetsDir+"/") {
+			// Bare names refer to the snippets folder
+			rel = SnippetsDir + "/" + rel
+			data, err = os.ReadFile(contentFile(rel))
+		}
+		if err != nil {
+			*warnings = append(*warnings, fmt.Sprintf("include %s not found", rel))
+			return []byte(`<span class="text-red-500">[Missing include: ` + rel + `]</span>`)
+		}
+		if !slices.Contains(*includes, rel) {
+			*includes = append(*includes, rel)
+		}
+		return expandIncludes(stripFrontmatter(data), depth+1, includes, warnings)
+	})
+}
+
+// utf8BOM is the byte order mark some Windows editors put at the start of
+// UTF-8 files
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// frontmatterDelimRegex matches a `---` line opening or closing the
+// frontmatter, allowing trailing whitespace and CRLF line endings
+var frontmatterDelimRegex = regexp.MustCompile(`(?m)^---[ \t]*\r?$`)
+
+// splitFrontmatter splits a leading `---` metadata block from the content
+// after it; ok is false when data has no complete block
+func splitFrontmatter(data []byte) (yaml, body []byte, ok bool) {
+	data = bytes.TrimPrefix(data, utf8BOM)
+	open := frontmatterDelimRegex.FindIndex(data)
+	if open == nil || open[0] != 0 {
+		return nil, data, false
+	}
+	rest := data[open[1]:]
+	end := frontmatterDelimRegex.FindIndex(rest)
+	if end == nil {
+		return nil, data, false
+	}
+	return rest[:end[0]], bytes.TrimPrefix(rest[end[1]:], []byte("\n")), true
+}
+
+// stripFrontmatter removes a leading `---` metadata block
+func stripFrontmatter(data []byte) []byte {
+	if _, body, ok := splitFrontmatter(data); ok {
+		return body
+	}
+	return data
+}
+
+// frontmatterBlock returns the YAML of a leading `---` metadata block, or
+// nil when there is none
+func frontmatterBlock(data []byte) []byte {
+	yaml, _, _ := splitFrontmatter(data)
+	return yaml
+}
+
+// checkFrontmatter drops a byte order mark, which hides the frontmatter
+// from the parser, and reports a block that is never closed
+func checkFrontmatter(source []byte, warnings *[]string) []byte {
+	if bytes.HasPrefix(source, utf8BOM) {
+		source = source[len(utf8BOM):]
+		if bytes.HasPrefix(source, []byte("---")) {
+			*warnings = append(*warnings, "the file starts with a UTF-8 byte order mark; save it without one")
+		}
+	}
+	if open := frontmatterDelimRegex.FindIndex(source); open != nil && open[0] == 0 {
+		if _, _, ok := splitFrontmatter(source); !ok {
+			*warnings = append(*warnings, "the frontmatter is not closed by a --- line, so it is ignored")
+		}
+	}
+	return source
+}
+
+// codeRegex matches code blocks and spans, where custom syntax is shown as is
+var codeRegex = regexp.MustCompile(`(?s)<pre\b.*?</pre>|<code\b.*?</code>`)
+
+// processCustomSyntax expands wiki links, ref and def tags outside of code
 func processCustomSyntax(content string) string {
+	var buf strings.Builder
+	last := 0
+	for _, loc := range codeRegex.FindAllStringIndex(content, -1) {
+		buf.WriteString(expandCustomSyntax(content[last:loc[0]]))
+		buf.WriteString(content[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	buf.WriteString(expandCustomSyntax(content[last:]))
+	return buf.String()
+}
+
+func expandCustomSyntax(content string) string {
 	// Wiki Links
 	content = wikiLinkRegex.ReplaceAllStringFunc(content, func(match string) string {
 		inner := match[2 : len(match)-2]
@@ -124,7 +464,9 @@ func processCustomSyntax(content string) string {
 		if !strings.HasPrefix(linkSlug, "/") {
 			linkSlug = "/" + linkSlug
 		}
-		return fmt.Sprintf(`<a href="#%s" class="text-blue-600 dark:text-blue-400 font-medium transition-colors hover:text-blue-800 dark:hover:text-blue-300">%s</a>`, linkSlug, linkText)
+		linkSlug = normalizeSlug(linkSlug)
+		// The page is looked up once every page is known, see ResolveWikiLinks
+		return fmt.Sprintf(`<a href="%s" %s="%s" class="text-blue-600 dark:text-blue-400 font-medium transition-colors hover:text-blue-800 dark:hover:text-blue-300">%s</a>`, pageHref(linkSlug), wikiLinkAttr, util.EscapeHTML([]byte(strings.TrimSpace(parts[0]))), linkText)
 	})
 
 	// Ref Tags
@@ -139,8 +481,20 @@ func processCustomSyntax(content string) string {
 		if !strings.HasPrefix(refSlug, "/") {
 			refSlug = "/" + refSlug
 		}
+		refSlug = normalizeSlug(refSlug)
+		// Replaced by the section once every page is rendered, see ResolveTransclusions
 		return fmt.Sprintf(`<div class="transclusion-placeholder p-4 border-l-4 border-purple-500 bg-gray-50 dark:bg-gray-800 my-4" data-slug="%s" data-id="%s"><span class="text-gray-400 text-sm animate-pulse">Loading referenced content...</span></div>`, refSlug, refID)
 	})
 
+	// Glossary Tags
+	content = defTagRegex.ReplaceAllStringFunc(content, func(match string) string {
+		word := strings.TrimSpace(match[6 : len(match)-2])
+		term, def, ok := siteGlossary.lookup(word)
+		if !ok {
+			return word
+		}
+		return glossaryLink(term, def, word)
+	})
+
 	return content
-}
\ No newline at end of file
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Review states of review.json
+const (
+	ReviewOverdue     = "overdue"
+	ReviewDueSoon     = "due-soon" // within cfg.ReviewSoonDays
+	ReviewScheduled   = "scheduled"
+	ReviewUnscheduled = "unscheduled" // reviewed, without a next date
+)
+
+// ReviewEntry is a page with `reviewed_by` or `review_due` frontmatter in
+// review.json
+type ReviewEntry struct {
+	Slug       string   `json:"slug"`
+	Title      string   `json:"title"`
+	ReviewedBy string   `json:"reviewed_by,omitempty"`
+	ReviewDue  string   `json:"review_due,omitempty"`
+	Status     string   `json:"status"`
+	Days       int      `json:"days"` // until the due date, negative when overdue
+	Owners     []string `json:"owners,omitempty"`
+}
+
+// reviewStatus returns the state of the review of a page and the days
+// left until it is due
+func reviewStatus(page PageData, now time.Time) (string, int) {
+	if page.ReviewDueAt.IsZero() {
+		return ReviewUnscheduled, 0
+	}
+	days := int(page.ReviewDueAt.Sub(now).Hours() / 24)
+	switch {
+	case page.ReviewDueAt.Before(now):
+		return ReviewOverdue, min(days, -1)
+	case days <= cfg.ReviewSoonDays:
+		return ReviewDueSoon, days
+	}
+	return ReviewScheduled, days
+}
+
+// ReviewReport lists the pages under review, the most urgent first;
+// pages without a due date come last.
+func ReviewReport(pages map[string]PageData, now time.Time) []ReviewEntry {
+	var report []ReviewEntry
+	for slug, page := range pages {
+		if page.ReviewedBy == "" && page.ReviewDue == "" {
+			continue
+		}
+		status, days := reviewStatus(page, now)
+		report = append(report, ReviewEntry{
+			Slug:       slug,
+			Title:      page.Title,
+			ReviewedBy: page.ReviewedBy,
+			ReviewDue:  page.ReviewDue,
+			Status:     status,
+			Days:       days,
+			Owners:     page.Owners,
+		})
+	}
+	sort.Slice(report, func(i, j int) bool {
+		a, b := report[i], report[j]
+		if (a.Status == ReviewUnscheduled) != (b.Status == ReviewUnscheduled) {
+			return b.Status == ReviewUnscheduled
+		}
+		if a.Days != b.Days {
+			return a.Days < b.Days
+		}
+		return a.Slug < b.Slug
+	})
+	return report
+}
+
+// WriteReviewReport writes review.json to the output folder
+func WriteReviewReport(report []ReviewEntry) error {
+	overdue := 0
+	for _, e := range report {
+		if e.Status == ReviewOverdue {
+			overdue++
+		}
+	}
+	if overdue > 0 {
+		fmt.Printf("%d pages are past their review date, see review.json\n", overdue)
+	}
+	if report == nil {
+		report = []ReviewEntry{}
+	}
+	data, _ := json.MarshalIndent(report, "", "  ")
+	return os.WriteFile(filepath.Join(cfg.OutputDir, "review.json"), data, 0644)
+}
+
+// AddReviewBadges puts a notice at the top of the pages past their review
+// date
+func AddReviewBadges(pages map[string]PageData, now time.Time) {
+	for slug, page := range pages {
+		if status, _ := reviewStatus(page, now); status != ReviewOverdue {
+			continue
+		}
+		page.Content = fmt.Sprintf("<p class=\"review-badge not-prose\">Review overdue since %s</p>\n", html.EscapeString(page.ReviewDue)) + page.Content
+		pages[slug] = page
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// SchemaFile is the JSON Schema of db.json, written next to it
+const SchemaFile = "db.schema.json"
+
+// Schema is the subset of JSON Schema (2020-12) used to describe db.json
+type Schema struct {
+	SchemaURI            string             `json:"$schema,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 interface{}        `json:"type,omitempty"` // a type name or a list of them
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	Defs                 map[string]*Schema `json:"$defs,omitempty"`
+}
+
+// SiteSchema describes db.json, generated from SiteData and the types it uses
+func SiteSchema() *Schema {
+	defs := make(map[string]*Schema)
+	root := typeSchema(reflect.TypeOf(SiteData{}), defs)
+	return &Schema{
+		SchemaURI: "https://json-schema.org/draft/2020-12/schema",
+		Title:     "go-blog db.json",
+		Ref:       root.Ref,
+		Defs:      defs,
+	}
+}
+
+// typeSchema returns the schema of the JSON encoding of t; structs are
+// added to defs and referenced by name.
+func typeSchema(t reflect.Type, defs map[string]*Schema) *Schema {
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Interface:
+		return &Schema{}
+	case reflect.Pointer:
+		return &Schema{AnyOf: []*Schema{typeSchema(t.Elem(), defs), {Type: "null"}}}
+	case reflect.Array:
+		return &Schema{Type: "array", Items: typeSchema(t.Elem(), defs)}
+	case reflect.Slice:
+		return &Schema{Type: []string{"array", "null"}, Items: typeSchema(t.Elem(), defs)}
+	case reflect.Map:
+		return &Schema{Type: []string{"object", "null"}, AdditionalProperties: typeSchema(t.Elem(), defs)}
+	case reflect.Struct:
+		ref := &Schema{Ref: "#/$defs/" + t.Name()}
+		if _, ok := defs[t.Name()]; ok {
+			return ref
+		}
+		s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+		defs[t.Name()] = s // before the fields, for recursive types
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if !f.IsExported() || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			s.Properties[name] = typeSchema(f.Type, defs)
+			if !strings.Contains(opts, "omitempty") {
+				s.Required = append(s.Required, name)
+			}
+		}
+		return ref
+	}
+	panic(fmt.Sprintf("no JSON schema for %s", t))
+}
+
+// WriteSchema writes db.schema.json to the output folder
+func WriteSchema(s *Schema) error {
+	data, _ := json.MarshalIndent(s, "", "  ")
+	return os.WriteFile(filepath.Join(cfg.OutputDir, SchemaFile), data, 0644)
+}
+
+// ValidateOutput checks db.json, written as name, against its schema,
+// reporting up to ten violations.
+func ValidateOutput(s *Schema, name string) error {
+	data, err := os.ReadFile(filepath.Join(cfg.OutputDir, name))
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return fmt.Errorf("db.json: %w", err)
+	}
+	var problems []string
+	s.validate(doc, "db.json", s.Defs, &problems)
+	if len(problems) == 0 {
+		return nil
+	}
+	if len(problems) > 10 {
+		problems = append(problems[:10], fmt.Sprintf("... and %d more", len(problems)-10))
+	}
+	return fmt.Errorf("db.json does not match %s:\n  %s", SchemaFile, strings.Join(problems, "\n  "))
+}
+
+func (s *Schema) validate(v interface{}, at string, defs map[string]*Schema, problems *[]string) {
+	if s.Ref != "" {
+		defs[strings.TrimPrefix(s.Ref, "#/$defs/")].validate(v, at, defs, problems)
+		return
+	}
+	if len(s.AnyOf) > 0 {
+		// Only pointers use anyOf: the pointed-to value or null
+		if v != nil {
+			s.AnyOf[0].validate(v, at, defs, problems)
+		}
+		return
+	}
+	if s.Type != nil && !s.allows(jsonType(v)) {
+		*problems = append(*problems, fmt.Sprintf("%s: got %s, want %v", at, jsonType(v), s.Type))
+		return
+	}
+	switch val := v.(type) {
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range val {
+				s.Items.validate(item, fmt.Sprintf("%s[%d]", at, i), defs, problems)
+			}
+		}
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := val[name]; !ok {
+				*problems = append(*problems, fmt.Sprintf("%s: missing %q", at, name))
+			}
+		}
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if prop, ok := s.Properties[k]; ok {
+				prop.validate(val[k], at+"."+k, defs, problems)
+			} else if s.AdditionalProperties != nil {
+				s.AdditionalProperties.validate(val[k], fmt.Sprintf("%s[%q]", at, k), defs, problems)
+			}
+		}
+	}
+}
+
+// allows reports whether the schema type admits the JSON type t
+func (s *Schema) allows(t string) bool {
+	var types []string
+	switch st := s.Type.(type) {
+	case string:
+		types = []string{st}
+	case []string:
+		types = st
+	}
+	for _, want := range types {
+		if want == t || (want == "number" && t == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonType names the JSON type of a decoded value
+func jsonType(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if _, err := val.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	}
+	return "object"
+}
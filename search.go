@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// SearchEntry is the searchable data of one page in search.json. The body
+// text is split at headings, so a hit can link straight to its section.
+type SearchEntry struct {
+	Slug     string              `json:"slug"`
+	Title    string              `json:"title"`
+	Headings []SearchHeading     `json:"headings,omitempty"`
+	Tags     []string            `json:"tags,omitempty"`
+	Terms    map[string][]string `json:"terms,omitempty"` // custom taxonomies, for filters like platform:linux
+	Text     string              `json:"text"`            // before the first heading
+	Sections []SearchSection     `json:"sections,omitempty"`
+}
+
+// SearchHeading lets a search hit jump to the matching section
+type SearchHeading struct {
+	Title string `json:"title"`
+	ID    string `json:"id"`
+}
+
+// SearchSection is the text from a heading up to the next one
+type SearchSection struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	Text  string `json:"text"`
+}
+
+// headingIDRegex matches the opening tag of a heading with an id
+var headingIDRegex = regexp.MustCompile(`<h[1-6][^>]*\sid="([^"]+)"`)
+
+// sectionMark wraps a heading id while the page is reduced to plain text
+const sectionMark = "\x00"
+
+// BuildSearchIndex collects titles, headings, tags and body text of every page
+func BuildSearchIndex(pages map[string]PageData) []SearchEntry {
+	index := make([]SearchEntry, 0, len(pages))
+	for slug, page := range pages {
+		titles := make(map[string]string, len(page.TOC))
+		entry := SearchEntry{Slug: slug, Title: page.Title}
+		for _, h := range page.TOC {
+			entry.Headings = append(entry.Headings, SearchHeading{Title: h.Title, ID: h.ID})
+			titles[h.ID] = h.Title
+		}
+		entry.Text, entry.Sections = searchSections(page.Content, titles)
+		if page.Category != "" {
+			entry.Tags = append(entry.Tags, page.Category)
+		}
+		entry.Tags = append(entry.Tags, page.Tags...)
+		entry.Tags = append(entry.Tags, page.Keywords...)
+		entry.Terms = page.Terms
+		index = append(index, entry)
+	}
+	sort.Slice(index, func(i, j int) bool { return index[i].Slug < index[j].Slug })
+	return index
+}
+
+// searchSections returns the plain text of rendered HTML, whitespace
+// collapsed and split at every heading with an id: the text before the
+// first heading, then one section per heading titled from titles (by id).
+func searchSections(content string, titles map[string]string) (string, []SearchSection) {
+	marked := headingIDRegex.ReplaceAllString(content, " "+sectionMark+"$1"+sectionMark+" $0")
+	var intro []string
+	var sections []SearchSection
+	var words []string
+	flush := func() {
+		if len(sections) == 0 {
+			intro = words
+		} else {
+			sections[len(sections)-1].Text = strings.Join(words, " ")
+		}
+		words = nil
+	}
+	for _, word := range strings.Fields(html.UnescapeString(plainText(marked))) {
+		if len(word) > 1 && strings.HasPrefix(word, sectionMark) && strings.HasSuffix(word, sectionMark) {
+			flush()
+			id := strings.Trim(word, sectionMark)
+			title := titles[id]
+			if title == "" {
+				title = id
+			}
+			sections = append(sections, SearchSection{ID: id, Title: title})
+			continue
+		}
+		words = append(words, word)
+	}
+	flush()
+	return strings.Join(intro, " "), sections
+}
+
+// WriteSearchIndex writes search.json to the output folder
+func WriteSearchIndex(index []SearchEntry, assets AssetManifest) error {
+	data, _ := json.Marshal(index)
+	return assets.WriteFile("search.json", data)
+}
+
+// PaletteEntry is one page of palette.json, kept terse so the quick switcher
+// can load it up front on large sites
+type PaletteEntry struct {
+	Title    string      `json:"t"`
+	Slug     string      `json:"s"`
+	Headings [][2]string `json:"h,omitempty"` // [title, id] of h2 and h3 headings
+}
+
+// BuildPalette lists page titles, slugs and top-level headings
+func BuildPalette(pages map[string]PageData) []PaletteEntry {
+	palette := make([]PaletteEntry, 0, len(pages))
+	for slug, page := range pages {
+		entry := PaletteEntry{Title: page.Title, Slug: slug}
+		for _, h := range page.TOC {
+			if h.Level <= 3 {
+				entry.Headings = append(entry.Headings, [2]string{h.Title, h.ID})
+			}
+		}
+		palette = append(palette, entry)
+	}
+	sort.Slice(palette, func(i, j int) bool { return palette[i].Slug < palette[j].Slug })
+	return palette
+}
+
+// WritePalette writes palette.json to the output folder
+func WritePalette(palette []PaletteEntry, assets AssetManifest) error {
+	data, _ := json.Marshal(palette)
+	return assets.WriteFile("palette.json", data)
+}
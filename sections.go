@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// SectionIndex is the name of the page introducing a content folder. Like
+// an index.md it is the page of the folder in the menu; its title and
+// weight also name and order the folder, and the pages in the folder are
+// listed below its content.
+const SectionIndex = "_index"
+
+// AddSectionListings appends the list of the pages in their folder, in menu
+// order, to the section pages at slugs
+func AddSectionListings(pages map[string]PageData, menu []*MenuItem, slugs []string) {
+	for _, slug := range slugs {
+		page, ok := pages[slug]
+		if !ok {
+			continue
+		}
+		children := menu
+		if slug != "/" {
+			folder := sectionFolder(menu, slug)
+			if folder == nil {
+				continue
+			}
+			children = folder.Children
+		}
+		var buf strings.Builder
+		for _, item := range children {
+			child, ok := pages[item.Slug]
+			if item.Slug == slug || !ok {
+				continue // folders without a page, links and the section itself
+			}
+			buf.WriteString(fmt.Sprintf("<li><a href=\"%s\">%s</a>", pageHref(item.Slug), html.EscapeString(child.Title)))
+			if child.Published != "" {
+				buf.WriteString(" <span class=\"text-sm text-gray-500\">" + html.EscapeString(child.Published) + "</span>")
+			}
+			if child.Description != "" {
+				buf.WriteString("<br><span class=\"text-sm\">" + html.EscapeString(child.Description) + "</span>")
+			}
+			buf.WriteString("</li>\n")
+		}
+		if buf.Len() == 0 {
+			continue
+		}
+		page.Content += "\n<ul class=\"section-pages\">\n" + buf.String() + "</ul>\n"
+		pages[slug] = page
+	}
+}
+
+// sectionFolder finds the menu folder whose page is at slug
+func sectionFolder(items []*MenuItem, slug string) *MenuItem {
+	for _, item := range items {
+		if item.IsFolder && item.Slug == slug {
+			return item
+		}
+		if found := sectionFolder(item.Children, slug); found != nil {
+			return found
+		}
+	}
+	return nil
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html"
+	"html/template"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Shortcodes are calls like {{< youtube dQw4w9WgXcQ >}} or
+// {{< figure src="./a.png" caption="Setup" >}} in markdown, optionally
+// wrapping content up to a closing {{< /name >}}. {{</* name */>}} writes the
+// call out as text instead.
+var (
+	shortcodeRegex       = regexp.MustCompile(`\{\{<\s*(/?)([\w-]+)((?:\s+(?:[\w-]+=)?(?:"[^"]*"|[^\s">]+))*)\s*>\}\}`)
+	shortcodeArgRegex    = regexp.MustCompile(`(?:([\w-]+)=)?(?:"([^"]*)"|([^\s"]+))`)
+	shortcodeEscapeRegex = regexp.MustCompile(`\{\{</\*(.*?)\*/>\}\}`)
+	shortcodeMarkRegex   = regexp.MustCompile(`GOBLOGSHORTCODE(\d+)X`)
+	shortcodeBlockRegex  = regexp.MustCompile(`<p>GOBLOGSHORTCODE(\d+)X</p>\n?`)
+)
+
+// ShortcodesDir is the folder of shortcode templates within the templates
+const ShortcodesDir = "shortcodes"
+
+//go:embed templates/shortcodes/*.html
+var defaultShortcodes embed.FS
+
+// ShortcodeCall is one use of a shortcode
+type ShortcodeCall struct {
+	Name   string
+	Args   []string          // positional arguments
+	Params map[string]string // name="value" arguments
+	Inner  template.HTML     // rendered markdown up to the closing tag, if any
+}
+
+// Get returns the named argument, or else the positional one at pos (-1
+// for none); missing arguments are empty.
+func (c ShortcodeCall) Get(name string, pos int) string {
+	if v, ok := c.Params[name]; ok {
+		return v
+	}
+	if pos >= 0 && pos < len(c.Args) {
+		return c.Args[pos]
+	}
+	return ""
+}
+
+// Shortcode renders a call to HTML
+type Shortcode func(call ShortcodeCall) (string, error)
+
+// shortcodeRegistry holds the shortcodes registered from Go code;
+// siteShortcodes adds the templates to them for the current build.
+var (
+	shortcodeRegistry = make(map[string]Shortcode)
+	siteShortcodes    = make(map[string]Shortcode)
+	shortcodeSources  []byte // the user's templates, part of the build cache key
+)
+
+// RegisterShortcode makes {{< name ... >}} call fn. Templates of the same
+// name take precedence.
+func RegisterShortcode(name string, fn Shortcode) {
+	shortcodeRegistry[name] = fn
+}
+
+// LoadShortcodes sets up the shortcodes of a build: the registered ones,
+// the built-in templates, and the templates in <dir>/shortcodes/<name>.html,
+// which replace a built-in of the same name. Templates use html/template
+// with a ShortcodeCall as data: {{ .Get "id" 0 }}, {{ .Params.title }}, {{ .Inner }}.
+func LoadShortcodes(dir string) error {
+	siteShortcodes = make(map[string]Shortcode, len(shortcodeRegistry))
+	for name, fn := range shortcodeRegistry {
+		siteShortcodes[name] = fn
+	}
+	shortcodeSources = nil
+
+	builtin, _ := fs.Glob(defaultShortcodes, "templates/shortcodes/*.html")
+	for _, file := range builtin {
+		data, _ := defaultShortcodes.ReadFile(file)
+		if err := addShortcodeTemplate(path.Base(file), data); err != nil {
+			return err
+		}
+	}
+	if dir == "" {
+		return nil
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, ShortcodesDir, "*.html"))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if err := addShortcodeTemplate(filepath.Base(file), data); err != nil {
+			return err
+		}
+		shortcodeSources = append(shortcodeSources, file...)
+		shortcodeSources = append(shortcodeSources, data...)
+	}
+	return nil
+}
+
+func addShortcodeTemplate(file string, data []byte) error {
+	name := strings.TrimSuffix(file, ".html")
+	tmpl, err := template.New(file).Parse(string(data))
+	if err != nil {
+		return err
+	}
+	siteShortcodes[name] = func(call ShortcodeCall) (string, error) {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, call); err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(buf.String()), nil
+	}
+	return nil
+}
+
+// parseShortcodeArgs splits the arguments of a call into positional and
+// named ones
+func parseShortcodeArgs(call *ShortcodeCall, args string) {
+	call.Params = make(map[string]string)
+	for _, m := range shortcodeArgRegex.FindAllStringSubmatch(args, -1) {
+		value := m[2] + m[3]
+		if m[1] != "" {
+			call.Params[m[1]] = value
+		} else {
+			call.Args = append(call.Args, value)
+		}
+	}
+}
+
+// shortcodeOutput holds the HTML of the shortcodes of a page while its
+// markdown is rendered; the calls are replaced by markers, so the HTML is
+// left alone by the markdown parser.
+type shortcodeOutput []string
+
+// expand replaces the shortcode calls in source with markers. Calls to
+// unknown shortcodes or failing ones are reported and left as they are, as
+// are calls in code.
+func (out *shortcodeOutput) expand(source []byte, warnings *[]string) []byte {
+	source = shortcodeEscapeRegex.ReplaceAllFunc(source, func(m []byte) []byte {
+		literal := "{{<" + string(shortcodeEscapeRegex.FindSubmatch(m)[1]) + ">}}"
+		return out.mark(html.EscapeString(literal))
+	})
+
+	var buf bytes.Buffer
+	rest := source
+	code := markdownCode(source)
+	for {
+		loc := shortcodeRegex.FindSubmatchIndex(rest)
+		if loc == nil {
+			break
+		}
+		if end, ok := codeEnd(code, len(source)-len(rest)+loc[0]); ok {
+			end -= len(source) - len(rest)
+			buf.Write(rest[:end])
+			rest = rest[end:]
+			continue
+		}
+		closing, name := loc[3] > loc[2], string(rest[loc[4]:loc[5]])
+		buf.Write(rest[:loc[0]])
+		tag := rest[loc[0]:loc[1]]
+		rest = rest[loc[1]:]
+		if closing {
+			*warnings = append(*warnings, fmt.Sprintf("shortcode {{< /%s >}} without an opening tag", name))
+			buf.Write(tag)
+			continue
+		}
+		call := ShortcodeCall{Name: name}
+		parseShortcodeArgs(&call, string(tag[loc[6]-loc[0]:loc[7]-loc[0]]))
+		if end := closingShortcode(rest, name); end != nil {
+			call.Inner = template.HTML(out.renderInner(rest[:end[0]], warnings))
+			rest = rest[end[1]:]
+		}
+		fn, ok := siteShortcodes[name]
+		if !ok {
+			*warnings = append(*warnings, fmt.Sprintf("unknown shortcode %q", name))
+			buf.Write(tag)
+			continue
+		}
+		result, err := fn(call)
+		if err != nil {
+			*warnings = append(*warnings, fmt.Sprintf("shortcode %s: %v", name, err))
+			buf.Write(tag)
+			continue
+		}
+		buf.Write(out.mark(result))
+	}
+	buf.Write(rest)
+	return buf.Bytes()
+}
+
+// closingShortcode finds the {{< /name >}} tag in source, outside of code
+func closingShortcode(source []byte, name string) []int {
+	code := markdownCode(source)
+	for _, loc := range shortcodeRegex.FindAllSubmatchIndex(source, -1) {
+		if _, ok := codeEnd(code, loc[0]); ok {
+			continue
+		}
+		if loc[3] > loc[2] && string(source[loc[4]:loc[5]]) == name {
+			return loc
+		}
+	}
+	return nil
+}
+
+// renderInner renders the markdown wrapped by a shortcode
+func (out *shortcodeOutput) renderInner(source []byte, warnings *[]string) string {
+	var buf bytes.Buffer
+	if err := mdParser.Convert(out.expand(source, warnings), &buf); err != nil {
+		*warnings = append(*warnings, err.Error())
+	}
+	return strings.TrimSpace(out.replace(buf.String()))
+}
+
+// mark stores the HTML of a call and returns its marker
+func (out *shortcodeOutput) mark(html string) []byte {
+	*out = append(*out, html)
+	return []byte("GOBLOGSHORTCODE" + strconv.Itoa(len(*out)-1) + "X")
+}
+
+// replace puts the shortcode HTML in place of the markers of rendered HTML,
+// dropping the paragraph goldmark wraps around a call on a line of its own
+func (out shortcodeOutput) replace(content string) string {
+	if len(out) == 0 {
+		return content
+	}
+	content = shortcodeBlockRegex.ReplaceAllStringFunc(content, func(m string) string {
+		if result := out.lookup(m); strings.HasPrefix(result, "<") {
+			return result + "\n"
+		}
+		return m
+	})
+	return shortcodeMarkRegex.ReplaceAllStringFunc(content, out.lookup)
+}
+
+// plain replaces the markers in extracted text (descriptions, headings)
+// with the text of the shortcode HTML
+func (out shortcodeOutput) plain(text string) string {
+	if len(out) == 0 {
+		return text
+	}
+	return strings.Join(strings.Fields(shortcodeMarkRegex.ReplaceAllStringFunc(text, func(m string) string {
+		return html.UnescapeString(plainText(out.lookup(m)))
+	})), " ")
+}
+
+// lookup returns the HTML of the shortcode marked in m
+func (out shortcodeOutput) lookup(m string) string {
+	i, _ := strconv.Atoi(shortcodeMarkRegex.FindStringSubmatch(m)[1])
+	if i < len(out) {
+		return out[i]
+	}
+	return m
+}
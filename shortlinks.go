@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// ShortLinksSlug is the route of the generated map of short links; the
+// links themselves live below it, set by `short` frontmatter
+const ShortLinksSlug = "/r"
+
+// shortLink normalizes the `short` frontmatter of a page: "setup" and
+// "/r/setup" both give /r/setup
+func shortLink(value string) string {
+	name := strings.TrimPrefix(strings.Trim(strings.TrimSpace(value), "/"), strings.TrimPrefix(ShortLinksSlug, "/")+"/")
+	if name == "" {
+		return ""
+	}
+	return redirectSlug(ShortLinksSlug + "/" + name)
+}
+
+// hasShortLinks reports whether any page sets `short`
+func hasShortLinks(pages map[string]PageData) bool {
+	for _, page := range pages {
+		if page.Short != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// BuildShortLinksPage lists the short links redirecting to pages, for
+// slides, support replies and print. Links dropped by CollectRedirects are
+// left out.
+func BuildShortLinksPage(pages map[string]PageData, redirects map[string]string) PageData {
+	var buf strings.Builder
+	buf.WriteString("<table>\n<thead><tr><th>Short link</th><th>Page</th></tr></thead>\n<tbody>\n")
+	for _, slug := range sortedKeys(pages) {
+		page := pages[slug]
+		if page.Short == "" || redirects[page.Short] != slug {
+			continue
+		}
+		buf.WriteString(fmt.Sprintf("<tr><td><code>%s</code></td><td><a href=\"%s\">%s</a></td></tr>\n",
+			html.EscapeString(siteURL(page.Short)), pageHref(slug), html.EscapeString(page.Title)))
+	}
+	buf.WriteString("</tbody>\n</table>\n")
+	return PageData{
+		Title:       "Short Links",
+		Content:     buf.String(),
+		Description: "Short URLs of pages.",
+		HideTOC:     true,
+		HideMeta:    true,
+		NoIndex:     true,
+	}
+}
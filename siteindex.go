@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// SiteIndexSlug is the route of the generated site index
+const SiteIndexSlug = "/sitemap"
+
+// BuildSiteIndexPage renders the whole menu tree as nested lists, with the
+// publish and update dates of each page.
+func BuildSiteIndexPage(menu []*MenuItem, pages map[string]PageData) PageData {
+	var buf strings.Builder
+	writeSiteIndex(&buf, menu, pages)
+	return PageData{
+		Title:       "Site Index",
+		Content:     buf.String(),
+		Description: "Every page of the site.",
+		HideTOC:     true,
+		HideMeta:    true,
+	}
+}
+
+func writeSiteIndex(buf *strings.Builder, items []*MenuItem, pages map[string]PageData) {
+	buf.WriteString("<ul class=\"site-index\">\n")
+	for _, item := range items {
+		switch {
+		case item.Separator:
+			continue
+		case item.URL != "":
+			buf.WriteString(fmt.Sprintf("<li><a href=\"%s\">%s</a>", html.EscapeString(item.URL), html.EscapeString(item.Title)))
+		case item.Slug != "":
+			buf.WriteString(fmt.Sprintf("<li><a href=\"%s\">%s</a>", pageHref(item.Slug), html.EscapeString(item.Title)))
+			page := pages[item.Slug]
+			if dates := pageDates(page); dates != "" {
+				buf.WriteString(" <span class=\"text-sm text-gray-500\">" + dates + "</span>")
+			}
+		default:
+			buf.WriteString("<li><strong>" + html.EscapeString(item.Title) + "</strong>")
+		}
+		if len(item.Children) > 0 {
+			buf.WriteString("\n")
+			writeSiteIndex(buf, item.Children, pages)
+		}
+		buf.WriteString("</li>\n")
+	}
+	buf.WriteString("</ul>\n")
+}
+
+// pageDates formats the dates of a page as "2026-01-01, updated 2026-01-03"
+func pageDates(page PageData) string {
+	dates := html.EscapeString(page.Published)
+	if page.Updated != "" && page.Updated != page.Published {
+		if dates != "" {
+			dates += ", "
+		}
+		dates += "updated " + html.EscapeString(page.Updated)
+	}
+	return dates
+}
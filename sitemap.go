@@ -2,28 +2,215 @@ package main
 
 import (
 	"bytes"
+	"encoding/xml"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 )
 
-func GenerateXMLSitemap(slugs []string) error {
+// Hard limits of the sitemap protocol for a single file
+const (
+	sitemapLimitURLs  = 50000
+	sitemapLimitBytes = 50 * 1024 * 1024
+)
+
+const (
+	sitemapHeader = `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+		`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">` + "\n"
+	sitemapFooter = `</urlset>`
+)
+
+// GenerateXMLSitemap writes sitemap.xml. When the URLs exceed SitemapMaxURLs
+// (or the protocol limits) they are split into sitemap-N.xml files and
+// sitemap.xml becomes a sitemap index.
+func GenerateXMLSitemap(slugs []string, pages map[string]PageData) error {
+	now := siteTime()
+	maxURLs := cfg.SitemapMaxURLs
+	if maxURLs <= 0 || maxURLs > sitemapLimitURLs {
+		maxURLs = sitemapLimitURLs
+	}
+
+	var files []*bytes.Buffer
+	var modified []time.Time // newest lastmod of each file
+	var buf *bytes.Buffer
+	count := 0
+	for _, slug := range slugs {
+		mod := lastmod(pages[slug], now)
+		entry := sitemapEntry(slug, mod.Format("2006-01-02"), changefreq(slug, pages[slug], now))
+		if buf == nil || count >= maxURLs || buf.Len()+len(entry)+len(sitemapFooter) > sitemapLimitBytes {
+			if buf != nil {
+				buf.WriteString(sitemapFooter)
+			}
+			buf = bytes.NewBufferString(sitemapHeader)
+			files = append(files, buf)
+			modified = append(modified, mod)
+			count = 0
+		}
+		buf.WriteString(entry)
+		if mod.After(modified[len(modified)-1]) {
+			modified[len(modified)-1] = mod
+		}
+		count++
+	}
+	if buf == nil {
+		buf = bytes.NewBufferString(sitemapHeader)
+		files = append(files, buf)
+	}
+	buf.WriteString(sitemapFooter)
+
+	if len(files) == 1 {
+		return os.WriteFile(filepath.Join(cfg.OutputDir, "sitemap.xml"), files[0].Bytes(), 0644)
+	}
+
+	var index bytes.Buffer
+	index.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	index.WriteString(`<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">` + "\n")
+	for i, file := range files {
+		name := fmt.Sprintf("sitemap-%d.xml", i+1)
+		if err := os.WriteFile(filepath.Join(cfg.OutputDir, name), file.Bytes(), 0644); err != nil {
+			return err
+		}
+		index.WriteString("  <sitemap>\n")
+		index.WriteString(fmt.Sprintf("    <loc>%s</loc>\n", xmlEscape(siteURL(name))))
+		index.WriteString(fmt.Sprintf("    <lastmod>%s</lastmod>\n", modified[i].Format("2006-01-02")))
+		index.WriteString("  </sitemap>\n")
+	}
+	index.WriteString(`</sitemapindex>`)
+	return os.WriteFile(filepath.Join(cfg.OutputDir, "sitemap.xml"), index.Bytes(), 0644)
+}
+
+// lastmod is the last change of a page: its update date, else its publish
+// date, else the modification time of its file, else now (generated pages).
+func lastmod(page PageData, now time.Time) time.Time {
+	for _, t := range []time.Time{page.UpdatedAt, page.PublishedAt, page.ModTime} {
+		if !t.IsZero() {
+			return t
+		}
+	}
+	return now
+}
+
+func sitemapEntry(slug, lastmod, freq string) string {
+	var buf bytes.Buffer
+	buf.WriteString("  <url>\n")
+	buf.WriteString(fmt.Sprintf("    <loc>%s</loc>\n", xmlEscape(preferredURL(slug))))
+	buf.WriteString(fmt.Sprintf("    <lastmod>%s</lastmod>\n", lastmod))
+	buf.WriteString(fmt.Sprintf("    <changefreq>%s</changefreq>\n", freq))
+	buf.WriteString("  </url>\n")
+	return buf.String()
+}
+
+// validChangefreq lists the values the sitemap protocol allows
+var validChangefreq = map[string]bool{
+	"always": true, "hourly": true, "daily": true, "weekly": true, "monthly": true, "yearly": true, "never": true,
+}
+
+// changefreq picks the sitemap change frequency of a page: its `changefreq`
+// frontmatter, else the longest matching slug prefix of the configured
+// overrides, else a guess from how long it went between publish and update,
+// aging pages as of now.
+func changefreq(slug string, page PageData, now time.Time) string {
+	if page.Changefreq != "" {
+		return page.Changefreq
+	}
+	best := -1
+	freq := ""
+	for prefix, f := range cfg.SitemapChangefreq {
+		if (slug == prefix || strings.HasPrefix(slug, strings.TrimSuffix(prefix, "/")+"/")) && len(prefix) > best {
+			best, freq = len(prefix), f
+		}
+	}
+	if freq != "" {
+		return freq
+	}
+
+	published, updated := page.PublishedAt, page.UpdatedAt
+	if published.IsZero() {
+		return "weekly"
+	}
+	if !updated.After(published) {
+		// Never revised: recent pages may still change, old ones rarely do
+		if now.Sub(published) > 365*24*time.Hour {
+			return "yearly"
+		}
+		return "monthly"
+	}
+	switch gap := updated.Sub(published); {
+	case gap <= 24*time.Hour:
+		return "daily"
+	case gap <= 7*24*time.Hour:
+		return "weekly"
+	case gap <= 31*24*time.Hour:
+		return "monthly"
+	}
+	return "yearly"
+}
+
+// newsWindow is how long a post stays in the news sitemap
+const newsWindow = 48 * time.Hour
+
+// GenerateNewsSitemap writes sitemap-news.xml with the pages published
+// within the last 48 hours, in the Google News sitemap format.
+func GenerateNewsSitemap(pages map[string]PageData) error {
+	now := siteTime()
+	var slugs []string
+	for slug, page := range pages {
+		published, ok := parseDate(page.Published)
+		if ok && page.Indexed() && !published.After(now) && now.Sub(published) <= newsWindow {
+			slugs = append(slugs, slug)
+		}
+	}
+	sort.Strings(slugs)
+
 	var buf bytes.Buffer
 	buf.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
-	buf.WriteString(`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">` + "\n")
-	today := time.Now().Format("2006-01-02")
+	buf.WriteString(`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9" xmlns:news="http://www.google.com/schemas/sitemap-news/0.9">` + "\n")
 	for _, slug := range slugs {
-		fullUrl := BaseURL + "/#" + slug
-		if slug == "/" {
-			fullUrl = BaseURL + "/"
-		}
+		page := pages[slug]
+		published, _ := parseDate(page.Published)
 		buf.WriteString("  <url>\n")
-		buf.WriteString(fmt.Sprintf("    <loc>%s</loc>\n", fullUrl))
-		buf.WriteString(fmt.Sprintf("    <lastmod>%s</lastmod>\n", today))
-		buf.WriteString("    <changefreq>weekly</changefreq>\n")
+		buf.WriteString(fmt.Sprintf("    <loc>%s</loc>\n", xmlEscape(preferredURL(slug))))
+		buf.WriteString("    <news:news>\n")
+		buf.WriteString("      <news:publication>\n")
+		buf.WriteString(fmt.Sprintf("        <news:name>%s</news:name>\n", xmlEscape(cfg.NewsPublicationName)))
+		buf.WriteString(fmt.Sprintf("        <news:language>%s</news:language>\n", xmlEscape(cfg.NewsLanguage)))
+		buf.WriteString("      </news:publication>\n")
+		buf.WriteString(fmt.Sprintf("      <news:publication_date>%s</news:publication_date>\n", published.Format(time.RFC3339)))
+		buf.WriteString(fmt.Sprintf("      <news:title>%s</news:title>\n", xmlEscape(page.Title)))
+		buf.WriteString("    </news:news>\n")
 		buf.WriteString("  </url>\n")
 	}
 	buf.WriteString(`</urlset>`)
-	return os.WriteFile(filepath.Join(OutputDir, "sitemap.xml"), buf.Bytes(), 0644)
-}
\ No newline at end of file
+	return os.WriteFile(filepath.Join(cfg.OutputDir, "sitemap-news.xml"), buf.Bytes(), 0644)
+}
+
+// dateLayouts are the accepted formats of frontmatter dates
+var dateLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"}
+
+// parseDate parses a frontmatter date, reporting whether it was valid
+func parseDate(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// siteTime is the moment the site is built for: the release_at date, or now
+func siteTime() time.Time {
+	if t, ok := parseDate(cfg.ReleaseAt); ok {
+		return t
+	}
+	return time.Now()
+}
+
+func xmlEscape(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
@@ -0,0 +1,13 @@
+package main
+
+import (
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// normalizeSlug lowercases a slug and converts it to Unicode NFC, so that
+// different file name casings and encodings resolve to the same page.
+func normalizeSlug(slug string) string {
+	return norm.NFC.String(strings.ToLower(slug))
+}
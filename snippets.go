@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SnippetsDir is the content folder holding reusable blocks. Its files are
+// not pages, they are only reachable through {{include:...}} and {{ref:...}}.
+const SnippetsDir = "_snippets"
+
+// snippetSlug returns the slug a snippet is referenced by, e.g.
+// "_snippets/beta.md" -> "/_snippets/beta"
+func snippetSlug(rel string) string {
+	return normalizeSlug("/" + strings.TrimSuffix(filepath.ToSlash(rel), ".md"))
+}
+
+// LoadSnippets renders every snippet so that refs can transclude them,
+// keyed by slug. Snippets' own includes are added to used.
+func LoadSnippets(cache *BuildCache, used map[string]bool) (map[string]string, error) {
+	root := filepath.Join(cfg.InputDir, SnippetsDir)
+	if _, err := os.Stat(root); os.IsNotExist(err) {
+		return nil, nil
+	}
+	snippets := make(map[string]string)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".md" {
+			return nil
+		}
+		relPath := contentRel(path)
+		source, warning := readMarkdown(path)
+		if warning != "" {
+			fmt.Printf("Warning: %s: %s\n", path, warning)
+		}
+		if source == nil {
+			return nil
+		}
+		result, err := cache.Render(relPath, source)
+		if err != nil {
+			fmt.Printf("Warning: %s: %v, skipping it\n", path, err)
+			return nil
+		}
+		for _, w := range result.Warnings {
+			fmt.Printf("Warning: %s: %s\n", path, w)
+		}
+		markSnippetUse(used, result)
+		snippets[snippetSlug(relPath)] = result.HTML
+		return nil
+	})
+	return snippets, err
+}
+
+// markSnippetUse records the snippets a page includes or transcludes
+func markSnippetUse(used map[string]bool, result *RenderResult) {
+	for _, rel := range result.Includes {
+		used[snippetSlug(rel)] = true
+	}
+	for _, slug := range result.Refs {
+		used[slug] = true
+	}
+}
+
+// reportUnusedSnippets lists snippets nothing refers to
+func reportUnusedSnippets(snippets map[string]string, used map[string]bool) {
+	var unused []string
+	for slug := range snippets {
+		if !used[slug] {
+			unused = append(unused, slug)
+		}
+	}
+	if len(unused) == 0 {
+		return
+	}
+	sort.Strings(unused)
+	fmt.Println("Unused snippets:")
+	for _, slug := range unused {
+		fmt.Println("  " + strings.TrimPrefix(slug, "/") + ".md")
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+// AddSocialMeta fills in the Open Graph and Twitter Card tags of every page
+// from its title, description, URL and image. Tags set in the frontmatter
+// `meta:` map are kept. The default social_image is linked under its name
+// in assets.
+func AddSocialMeta(pages map[string]PageData, assets AssetManifest) {
+	for slug, page := range pages {
+		description := page.Description
+		if description == "" {
+			description = cfg.Description
+		}
+		url := canonicalURL(slug, page)
+		image := page.Image
+		if image == "" && cfg.SocialImage != "" {
+			image = assets.Link(absoluteLink(cfg.SocialImage))
+		}
+		ogType := "website"
+		if !page.PublishedAt.IsZero() {
+			ogType = "article"
+		}
+		card := "summary"
+		if image != "" {
+			card = "summary_large_image"
+		}
+
+		tags := map[string]string{
+			"og:site_name":        cfg.Title,
+			"og:title":            page.Title,
+			"og:description":      description,
+			"og:url":              url,
+			"og:type":             ogType,
+			"twitter:card":        card,
+			"twitter:title":       page.Title,
+			"twitter:description": description,
+		}
+		if image != "" {
+			tags["og:image"] = image
+			tags["twitter:image"] = image
+		}
+		if cfg.TwitterSite != "" {
+			tags["twitter:site"] = cfg.TwitterSite
+		}
+		if page.Meta == nil {
+			page.Meta = make(map[string]string, len(tags))
+		}
+		for key, value := range tags {
+			if _, ok := page.Meta[key]; !ok {
+				page.Meta[key] = value
+			}
+		}
+		pages[slug] = page
+	}
+}
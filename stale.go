@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// StaleEntry is a page due for review in stale.json
+type StaleEntry struct {
+	Slug    string   `json:"slug"`
+	Title   string   `json:"title"`
+	Section string   `json:"section,omitempty"`
+	Updated string   `json:"updated"` // last update, or publish date when never updated
+	Days    int      `json:"days"`    // since that date
+	Owners  []string `json:"owners,omitempty"`
+}
+
+// staleMonths returns the review interval of a section, 0 when it is exempt
+func staleMonths(section string) int {
+	if months, ok := cfg.StaleSections[section]; ok {
+		return months
+	}
+	return cfg.StaleMonths
+}
+
+// StaleReport lists the dated pages not updated within the review interval
+// of their section, oldest first.
+func StaleReport(pages map[string]PageData, now time.Time) []StaleEntry {
+	var report []StaleEntry
+	for slug, page := range pages {
+		section := sectionOf(slug)
+		months := staleMonths(section)
+		if months <= 0 {
+			continue
+		}
+		date, raw := page.UpdatedAt, page.Updated
+		if date.IsZero() {
+			date, raw = page.PublishedAt, page.Published
+		}
+		if date.IsZero() || date.After(now.AddDate(0, -months, 0)) {
+			continue
+		}
+		report = append(report, StaleEntry{
+			Slug:    slug,
+			Title:   page.Title,
+			Section: section,
+			Updated: raw,
+			Days:    int(now.Sub(date).Hours() / 24),
+			Owners:  page.Owners,
+		})
+	}
+	sort.Slice(report, func(i, j int) bool {
+		if report[i].Days != report[j].Days {
+			return report[i].Days > report[j].Days
+		}
+		return report[i].Slug < report[j].Slug
+	})
+	return report
+}
+
+// WriteStaleReport writes stale.json to the output folder
+func WriteStaleReport(report []StaleEntry) error {
+	if len(report) > 0 {
+		fmt.Printf("%d pages are due for review, see stale.json\n", len(report))
+	}
+	if report == nil {
+		report = []StaleEntry{}
+	}
+	data, _ := json.MarshalIndent(report, "", "  ")
+	return os.WriteFile(filepath.Join(cfg.OutputDir, "stale.json"), data, 0644)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// StatsSlug is the route of the optional generated statistics page
+const StatsSlug = "/stats"
+
+// wordsPerMinute is the reading speed used for reading time estimates
+const wordsPerMinute = 200
+
+// SiteStats summarizes the content of the site for audits
+type SiteStats struct {
+	Pages             int            `json:"pages"`
+	Sections          map[string]int `json:"sections"`
+	Categories        map[string]int `json:"categories"`
+	Tags              map[string]int `json:"tags"`
+	Words             int            `json:"words"`
+	AvgReadingMinutes float64        `json:"avg_reading_minutes"`
+	Newest            *DatedLink     `json:"newest,omitempty"`
+	Oldest            *DatedLink     `json:"oldest,omitempty"`
+}
+
+// DatedLink is a page reference with its publish date
+type DatedLink struct {
+	Title string `json:"title"`
+	Slug  string `json:"slug"`
+	Date  string `json:"date"`
+}
+
+// sectionOf returns the top-level folder of a slug, "" for root pages
+func sectionOf(slug string) string {
+	parts := strings.SplitN(strings.TrimPrefix(slug, "/"), "/", 2)
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[0]
+}
+
+// wordCount counts the words of rendered HTML content
+func wordCount(content string) int {
+	return len(strings.Fields(plainText(content)))
+}
+
+// ComputeStats aggregates page counts, words and dates
+func ComputeStats(pages map[string]PageData) SiteStats {
+	stats := SiteStats{Sections: make(map[string]int), Categories: make(map[string]int), Tags: make(map[string]int)}
+	var newestSlug, oldestSlug string
+	for slug, page := range pages {
+		stats.Pages++
+		section := sectionOf(slug)
+		if section == "" {
+			section = "(root)"
+		}
+		stats.Sections[section]++
+		if page.Category != "" {
+			stats.Categories[page.Category]++
+		}
+		for _, tag := range page.Tags {
+			stats.Tags[tag]++
+		}
+		stats.Words += wordCount(page.Content)
+
+		published, ok := parseDate(page.Published)
+		if !ok {
+			continue
+		}
+		// Ties are broken by slug so the output is stable
+		if newest, _ := parseDate(pages[newestSlug].Published); newestSlug == "" || published.After(newest) || (published.Equal(newest) && slug < newestSlug) {
+			newestSlug = slug
+		}
+		if oldest, _ := parseDate(pages[oldestSlug].Published); oldestSlug == "" || published.Before(oldest) || (published.Equal(oldest) && slug < oldestSlug) {
+			oldestSlug = slug
+		}
+	}
+	if stats.Pages > 0 {
+		avg := float64(stats.Words) / float64(stats.Pages) / wordsPerMinute
+		stats.AvgReadingMinutes = math.Round(avg*10) / 10
+	}
+	if newestSlug != "" {
+		stats.Newest = &DatedLink{Title: pages[newestSlug].Title, Slug: newestSlug, Date: pages[newestSlug].Published}
+		stats.Oldest = &DatedLink{Title: pages[oldestSlug].Title, Slug: oldestSlug, Date: pages[oldestSlug].Published}
+	}
+	return stats
+}
+
+// WriteStats writes stats.json to the output folder
+func WriteStats(stats SiteStats) error {
+	data, _ := json.MarshalIndent(stats, "", "  ")
+	return os.WriteFile(filepath.Join(cfg.OutputDir, "stats.json"), data, 0644)
+}
+
+// BuildStatsPage renders the statistics as a page
+func BuildStatsPage(stats SiteStats) PageData {
+	var buf strings.Builder
+	buf.WriteString("<h2 id=\"overview\">Overview</h2>\n<ul>\n")
+	buf.WriteString(fmt.Sprintf("<li>Pages: %d</li>\n", stats.Pages))
+	buf.WriteString(fmt.Sprintf("<li>Words: %d</li>\n", stats.Words))
+	buf.WriteString(fmt.Sprintf("<li>Average reading time: %.1f min</li>\n", stats.AvgReadingMinutes))
+	if stats.Newest != nil {
+		buf.WriteString(fmt.Sprintf("<li>Newest: <a href=\"%s\">%s</a> (%s)</li>\n", pageHref(stats.Newest.Slug), html.EscapeString(stats.Newest.Title), stats.Newest.Date))
+		buf.WriteString(fmt.Sprintf("<li>Oldest: <a href=\"%s\">%s</a> (%s)</li>\n", pageHref(stats.Oldest.Slug), html.EscapeString(stats.Oldest.Title), stats.Oldest.Date))
+	}
+	buf.WriteString("</ul>\n")
+	writeCountTable(&buf, "sections", "Sections", stats.Sections)
+	writeCountTable(&buf, "categories", "Categories", stats.Categories)
+	writeCountTable(&buf, "tags", "Tags", stats.Tags)
+
+	return PageData{
+		Title:       "Statistics",
+		Content:     buf.String(),
+		TOC:         []TOCEntry{{Title: "Overview", ID: "overview", Level: 2}, {Title: "Sections", ID: "sections", Level: 2}, {Title: "Categories", ID: "categories", Level: 2}, {Title: "Tags", ID: "tags", Level: 2}},
+		Description: "Content statistics of the site.",
+	}
+}
+
+func writeCountTable(buf *strings.Builder, id, title string, counts map[string]int) {
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	buf.WriteString(fmt.Sprintf("<h2 id=\"%s\">%s</h2>\n<table>\n<thead><tr><th>Name</th><th>Pages</th></tr></thead>\n<tbody>\n", id, title))
+	for _, name := range names {
+		buf.WriteString(fmt.Sprintf("<tr><td>%s</td><td>%d</td></tr>\n", html.EscapeString(name), counts[name]))
+	}
+	buf.WriteString("</tbody>\n</table>\n")
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Summary is what a summarizer returns for a page
+type Summary struct {
+	Summary  string   `json:"summary"`
+	Keywords []string `json:"keywords,omitempty"`
+}
+
+// Summarizer fills in missing descriptions by handing the page text to an
+// external command (text on stdin) or HTTP endpoint (POST {"text": ...}).
+// Both answer with a Summary as JSON; a command may also print plain text.
+// Results are cached by content hash so unchanged pages are never re-sent.
+type Summarizer struct {
+	Command   string
+	Endpoint  string
+	cachePath string
+	cache     map[string]Summary
+	dirty     bool
+}
+
+const summarizeTimeout = 60 * time.Second
+
+// NewSummarizer returns nil when no command or endpoint is configured
+func NewSummarizer(command, endpoint, cachePath string) *Summarizer {
+	if command == "" && endpoint == "" {
+		return nil
+	}
+	s := &Summarizer{Command: command, Endpoint: endpoint, cachePath: cachePath, cache: make(map[string]Summary)}
+	if data, err := os.ReadFile(cachePath); err == nil {
+		if err := json.Unmarshal(data, &s.cache); err != nil {
+			fmt.Println("Warning: ignoring corrupt summary cache:", err)
+		}
+	}
+	return s
+}
+
+// Summarize returns the cached or freshly computed summary of text
+func (s *Summarizer) Summarize(text string) (Summary, error) {
+	sum := sha256.Sum256([]byte(text))
+	key := hex.EncodeToString(sum[:])
+	if cached, ok := s.cache[key]; ok {
+		return cached, nil
+	}
+
+	var out []byte
+	var err error
+	if s.Command != "" {
+		out, err = s.runCommand(text)
+	} else {
+		out, err = s.callEndpoint(text)
+	}
+	if err != nil {
+		return Summary{}, err
+	}
+
+	var result Summary
+	if err := json.Unmarshal(out, &result); err != nil {
+		if s.Command == "" {
+			return Summary{}, fmt.Errorf("invalid response: %w", err)
+		}
+		result = Summary{Summary: strings.TrimSpace(string(out))}
+	}
+	s.cache[key] = result
+	s.dirty = true
+	return result, nil
+}
+
+func (s *Summarizer) runCommand(text string) ([]byte, error) {
+	args := strings.Fields(s.Command)
+	ctx, cancel := context.WithTimeout(context.Background(), summarizeTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	cmd.Stderr = os.Stderr
+	return cmd.Output()
+}
+
+func (s *Summarizer) callEndpoint(text string) ([]byte, error) {
+	body, _ := json.Marshal(map[string]string{"text": text})
+	client := http.Client{Timeout: summarizeTimeout}
+	resp, err := client.Post(s.Endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(resp.Body); err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("endpoint returned %s", resp.Status)
+	}
+	return buf.Bytes(), nil
+}
+
+// Save writes the cache back if anything new was summarized
+func (s *Summarizer) Save() error {
+	if !s.dirty {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(s.cachePath), 0755); err != nil {
+		return err
+	}
+	data, _ := json.MarshalIndent(s.cache, "", "  ")
+	return os.WriteFile(s.cachePath, data, 0644)
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Taxonomy names used in SiteData.Taxonomies
+const (
+	TaxonomyTags       = "tags"
+	TaxonomyCategories = "categories"
+)
+
+// Routes of the generated term pages (/tags, /tags/linux). Category pages
+// share their folder with the category feeds (category/releases/rss.xml).
+const (
+	TagsSlug       = "/tags"
+	CategoriesSlug = "/category"
+)
+
+// TermListing is one tag or category with the pages using it
+type TermListing struct {
+	Name  string     `json:"name"`
+	Pages []TermPage `json:"pages"`
+}
+
+// TermPage is a page of a term listing
+type TermPage struct {
+	Slug        string `json:"slug"`
+	Title       string `json:"title"`
+	Published   string `json:"published,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// termSlug turns a tag or category name into a URL segment,
+// e.g. "Release Notes" -> "release-notes"
+func termSlug(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(norm.NFC.String(strings.TrimSpace(name))) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+// parseTerms reads a frontmatter list, given either as a YAML list or a
+// comma separated string, dropping blanks and duplicates.
+func parseTerms(val interface{}) []string {
+	var raw []string
+	switch v := val.(type) {
+	case []interface{}:
+		for _, item := range v {
+			raw = append(raw, fmt.Sprintf("%v", item))
+		}
+	case string:
+		raw = strings.Split(v, ",")
+	}
+	var terms []string
+	seen := make(map[string]bool)
+	for _, t := range raw {
+		t = strings.TrimSpace(t)
+		if t == "" || seen[strings.ToLower(t)] {
+			continue
+		}
+		seen[strings.ToLower(t)] = true
+		terms = append(terms, t)
+	}
+	return terms
+}
+
+// taxonomySlug returns the route of a custom taxonomy's overview page
+func taxonomySlug(name string) string {
+	if slug := cfg.Taxonomies[name].Slug; slug != "" {
+		return slug
+	}
+	return "/" + name
+}
+
+// taxonomyNames returns the title (plural) and label (singular) of a
+// custom taxonomy
+func taxonomyNames(name string) (title, label string) {
+	t := cfg.Taxonomies[name]
+	title, label = t.Title, t.Label
+	if title == "" {
+		title = titleCase(name)
+	}
+	if label == "" {
+		label = title
+	}
+	return title, label
+}
+
+// ComputeTaxonomies counts the pages of every tag, category and term of the
+// custom taxonomies
+func ComputeTaxonomies(pages map[string]PageData) map[string]map[string]int {
+	tax := map[string]map[string]int{
+		TaxonomyTags:       make(map[string]int),
+		TaxonomyCategories: make(map[string]int),
+	}
+	for name := range cfg.Taxonomies {
+		tax[name] = make(map[string]int)
+	}
+	for _, page := range pages {
+		for _, tag := range page.Tags {
+			tax[TaxonomyTags][tag]++
+		}
+		if page.Category != "" {
+			tax[TaxonomyCategories][page.Category]++
+		}
+		for name, terms := range page.Terms {
+			for _, term := range terms {
+				tax[name][term]++
+			}
+		}
+	}
+	return tax
+}
+
+// ListTerms groups the pages by the terms returned for each of them, keyed
+// by term slug. Pages are listed newest first, undated ones last by title.
+func ListTerms(pages map[string]PageData, terms func(PageData) []string) map[string]*TermListing {
+	listings := make(map[string]*TermListing)
+	for slug, page := range pages {
+		for _, term := range terms(page) {
+			ts := termSlug(term)
+			if ts == "" {
+				continue
+			}
+			listing, ok := listings[ts]
+			if !ok {
+				listing = &TermListing{Name: term}
+				listings[ts] = listing
+			}
+			listing.Pages = append(listing.Pages, TermPage{Slug: slug, Title: page.Title, Published: page.Published, Description: page.Description})
+		}
+	}
+	for _, listing := range listings {
+		sort.Slice(listing.Pages, func(i, j int) bool {
+			a, b := listing.Pages[i], listing.Pages[j]
+			if a.Published != b.Published {
+				return a.Published > b.Published
+			}
+			return a.Title < b.Title
+		})
+	}
+	return listings
+}
+
+// BuildTermPages renders an overview page at base listing every term, and
+// a page per term (base/term-slug) listing its pages.
+func BuildTermPages(base, label, plural string, listings map[string]*TermListing) map[string]PageData {
+	generated := make(map[string]PageData)
+	if len(listings) == 0 {
+		return generated
+	}
+	var overview strings.Builder
+	overview.WriteString("<ul class=\"term-index\">\n")
+	for _, ts := range sortedKeys(listings) {
+		listing := listings[ts]
+		overview.WriteString(fmt.Sprintf("<li><a href=\"%s\">%s</a> <span class=\"text-sm text-gray-500\">(%d)</span></li>\n",
+			pageHref(base+"/"+ts), html.EscapeString(listing.Name), len(listing.Pages)))
+
+		var buf strings.Builder
+		buf.WriteString("<ul class=\"term-pages\">\n")
+		for _, p := range listing.Pages {
+			buf.WriteString(fmt.Sprintf("<li><a href=\"%s\">%s</a>", pageHref(p.Slug), html.EscapeString(p.Title)))
+			if p.Published != "" {
+				buf.WriteString(" <span class=\"text-sm text-gray-500\">" + html.EscapeString(p.Published) + "</span>")
+			}
+			if p.Description != "" {
+				buf.WriteString("<br><span class=\"text-sm\">" + html.EscapeString(p.Description) + "</span>")
+			}
+			buf.WriteString("</li>\n")
+		}
+		buf.WriteString("</ul>\n")
+		generated[base+"/"+ts] = PageData{
+			Title:       label + ": " + listing.Name,
+			Content:     buf.String(),
+			Description: fmt.Sprintf("Pages in %s %s.", strings.ToLower(label), listing.Name),
+			HideTOC:     true,
+			HideMeta:    true,
+		}
+	}
+	overview.WriteString("</ul>\n")
+	generated[base] = PageData{
+		Title:       plural,
+		Content:     overview.String(),
+		Description: "All " + strings.ToLower(plural) + " of the site.",
+		HideTOC:     true,
+		HideMeta:    true,
+	}
+	return generated
+}
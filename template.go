@@ -1,448 +1,106 @@
 package main
 
 import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"html/template"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
-func WriteAppShell(path string) error {
-	const html = `<!DOCTYPE html>
-<html lang="en" class="light">
-<head>
-    <meta charset="UTF-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>Docs</title>
-    <meta name="description" content="Documentation">
-    <link href="https://fonts.googleapis.com/css2?family=Inter:wght@400;500;600;700&display=swap" rel="stylesheet">
-    <link rel="stylesheet" href="https://cdn.lineicons.com/4.0/lineicons.css" />
-    <script src="https://cdn.tailwindcss.com?plugins=typography"></script>
-    <script>
-        tailwind.config = { 
-            darkMode: 'class', 
-            theme: { extend: { fontFamily: { sans: ['Inter', 'sans-serif'] } } } 
-        }
-    </script>
-    <script src="https://unpkg.com/vue@3/dist/vue.global.prod.js"></script>
-    <script src="https://unpkg.com/vue-router@4/dist/vue-router.global.prod.js"></script>
-    <style>
-        .admonition { border-left-width: 4px; padding: 1rem; margin-bottom: 1.5rem; border-radius: 0.375rem; background-color: #f9fafb; }
-        .dark .admonition { background-color: #1f2937; }
-        .admonition-title { font-weight: 700; margin-bottom: 0.5rem; display: flex; align-items: center; }
-        .admonition-title i { font-size: 1.25rem; margin-right: 0.5rem; }
-        .admonition-note { border-color: #3b82f6; } .admonition-note .admonition-title { color: #2563eb; }
-        .admonition-tip { border-color: #10b981; } .admonition-tip .admonition-title { color: #059669; }
-        .admonition-warning { border-color: #f59e0b; } .admonition-warning .admonition-title { color: #d97706; }
-        .admonition-important { border-color: #8b5cf6; } .admonition-important .admonition-title { color: #7c3aed; }
-        .admonition-caution { border-color: #ef4444; } .admonition-caution .admonition-title { color: #dc2626; }
-        .dark .prose { color: #d1d5db; }
-        .dark .prose h1, .dark .prose h2, .dark .prose h3, .dark .prose h4 { color: #f3f4f6; }
-        .dark .prose a { color: #60a5fa; }
-        .dark .prose strong { color: #f3f4f6; }
-        .dark .prose code { color: #fca5a5; }
-        .prose h1:first-of-type { display: none; }
-        .code-wrapper { position: relative; }
-        .copy-btn { 
-            position: absolute; top: 0.5rem; right: 0.5rem; 
-            padding: 0.25rem 0.5rem; font-size: 0.75rem; 
-            background: rgba(255,255,255,0.1); border: 1px solid rgba(255,255,255,0.2); 
-            border-radius: 0.25rem; color: #fff; cursor: pointer; opacity: 0; transition: opacity 0.2s;
-        }
-        .code-wrapper:hover .copy-btn { opacity: 1; }
-        .transclusion-placeholder h1, .transclusion-placeholder h2, .transclusion-placeholder h3 { margin-top: 0 !important; font-size: 1.2em; }
-        ::-webkit-scrollbar { width: 6px; }
-        ::-webkit-scrollbar-thumb { background: #cbd5e1; border-radius: 3px; }
-        .dark ::-webkit-scrollbar-thumb { background: #4b5563; }
-        html { scroll-behavior: smooth; }
-    </style>
-</head>
-<body class="bg-white dark:bg-gray-900 text-slate-800 dark:text-gray-200 h-screen overflow-hidden flex antialiased transition-colors duration-200">
-    <div id="app" class="w-full h-full flex relative">
-        <aside class="bg-gray-50 dark:bg-gray-800 border-r border-gray-200 dark:border-gray-700 w-64 flex-shrink-0 flex flex-col transition-all duration-300 absolute md:relative z-20 h-full"
-            :class="sidebarOpen ? 'translate-x-0' : '-translate-x-full md:w-0 md:overflow-hidden md:border-none'">
-            <div class="p-5 border-b border-gray-200 dark:border-gray-700 flex justify-between items-center bg-gray-50 dark:bg-gray-800">
-                <router-link to="/" class="font-bold text-lg tracking-tight text-slate-900 dark:text-white flex items-center">
-                    <i class="lni lni-library mr-2 text-blue-600"></i> Docs
-                </router-link>
-                <button @click="toggleSidebar" class="md:hidden text-gray-500 dark:text-gray-400">
-                    <i class="lni lni-close"></i>
-                </button>
-            </div>
-            <div class="p-3 border-b border-gray-200 dark:border-gray-700">
-                <div class="relative">
-                    <i class="lni lni-search-alt absolute left-3 top-2.5 text-gray-400"></i>
-                    <input v-model="searchQuery" type="text" placeholder="Search..." 
-                        class="w-full pl-9 pr-3 py-2 bg-white dark:bg-gray-700 border border-gray-300 dark:border-gray-600 rounded-md text-sm focus:outline-none focus:ring-2 focus:ring-blue-500 text-gray-900 dark:text-white">
-                </div>
-            </div>
-            <div v-if="searchQuery" class="flex-1 overflow-y-auto p-3 bg-white dark:bg-gray-800">
-                <ul v-if="filteredPages.length > 0" class="space-y-1">
-                    <li v-for="page in filteredPages" :key="page.slug">
-                        <router-link :to="page.slug" @click="searchQuery = ''" class="block px-2 py-1.5 text-sm text-slate-700 dark:text-gray-300 hover:bg-blue-50 dark:hover:bg-gray-700 hover:text-blue-600 rounded-md">
-                            <div class="font-medium">{{ page.title }}</div>
-                        </router-link>
-                    </li>
-                </ul>
-                <div v-else class="text-sm text-gray-500 text-center py-4">No results.</div>
-            </div>
-            <nav v-else class="flex-1 overflow-y-auto p-3">
-                 <div class="mb-1">
-                    <router-link to="/" class="block px-3 py-1.5 rounded-md text-sm font-medium transition-colors duration-200 flex items-center" 
-                        :class="$route.path === '/' ? 'bg-white dark:bg-gray-800 text-blue-600 dark:text-blue-400 shadow-sm border border-gray-100 dark:border-gray-700' : 'text-slate-600 dark:text-gray-400 hover:bg-gray-100 dark:hover:bg-gray-800 hover:text-slate-900 dark:hover:text-gray-200'">
-                        <i class="lni lni-home mr-2"></i> Home
-                    </router-link>
-                </div>
-                <sidebar-item v-for="item in filteredMenu" :key="item.title" :item="item"></sidebar-item>
-            </nav>
-        </aside>
-
-        <div class="flex-1 flex flex-col h-full overflow-hidden w-full relative bg-white dark:bg-gray-900">
-            <header class="h-14 border-b border-gray-100 dark:border-gray-800 flex items-center justify-between px-4 flex-shrink-0 bg-white/80 dark:bg-gray-900/80 backdrop-blur-sm z-10">
-                <div class="flex items-center">
-                    <button @click="toggleSidebar" class="p-2 -ml-2 text-gray-400 hover:text-gray-700 dark:hover:text-gray-200 rounded-md hover:bg-gray-100 dark:hover:bg-gray-800">
-                        <i class="lni lni-menu text-xl"></i>
-                    </button>
-                    <div class="ml-4 font-medium text-slate-400 text-sm truncate">/ {{ currentPage.title }}</div>
-                </div>
-                <button @click="toggleDarkMode" class="p-2 text-gray-400 hover:text-yellow-500 dark:hover:text-yellow-300 transition-colors">
-                    <i v-if="isDark" class="lni lni-sun text-lg"></i>
-                    <i v-else class="lni lni-night text-lg"></i>
-                </button>
-            </header>
-
-            <div v-if="loading" class="flex-1 flex items-center justify-center">
-                <div class="animate-spin rounded-full h-8 w-8 border-b-2 border-blue-600"></div>
-            </div>
-
-            <div v-else class="flex-1 overflow-hidden flex">
-                <main class="flex-1 overflow-y-auto p-8 lg:p-12 scroll-smooth" ref="mainScroll">
-                    <div class="max-w-3xl mx-auto flex flex-col min-h-[calc(100vh-8rem)]">
-                        <div class="flex-1">
-                            <router-view v-slot="{ Component }">
-                                <transition name="fade" mode="out-in">
-                                    <component :is="Component" :data="currentPage" :menu="menu" :flat-menu="flatMenu" />
-                                </transition>
-                            </router-view>
-                        </div>
-                        <footer class="mt-16 pt-8 border-t border-gray-100 dark:border-gray-800 text-center text-sm text-gray-400 dark:text-gray-600">
-                            <div class="mb-2">
-                                <router-link to="/sitemap" class="hover:text-blue-600 dark:hover:text-blue-400 transition-colors">Sitemap</router-link>
-                            </div>
-                            <div>
-                                Powered by &copy; {{ new Date().getFullYear() }}
-                            </div>
-                        </footer>
-                    </div>
-                </main>
-                <aside v-if="currentPage.toc && currentPage.toc.length > 0" class="hidden xl:block w-64 border-l border-gray-100 dark:border-gray-800 bg-white dark:bg-gray-900 flex-shrink-0 overflow-y-auto p-8">
-                    <div class="sticky top-0">
-                        <h5 class="text-xs font-semibold text-gray-400 uppercase tracking-wider mb-4">On this page</h5>
-                        <nav class="relative border-l border-gray-100 dark:border-gray-800 ml-1">
-                             <template v-for="item in nestedToc" :key="item.id">
-                                <div class="mb-2">
-                                    <div class="flex items-center justify-between group">
-                                        <a @click.prevent="scrollToHeader(item.id); toggleToc(item.id, true)" :href="'#'+item.id"
-                                           class="block text-sm transition-colors truncate cursor-pointer pl-4 -ml-px border-l-2 border-transparent hover:border-blue-500 hover:text-blue-600 flex-1"
-                                           :class="expandedTocId === item.id ? 'text-blue-600 font-medium border-blue-500' : 'text-slate-500 dark:text-gray-400'">
-                                           {{ item.title }}
-                                        </a>
-                                         <button v-if="item.children && item.children.length" @click.stop="toggleToc(item.id)" class="p-1 mr-1 text-gray-400 hover:text-blue-600 rounded-md hover:bg-gray-100 dark:hover:bg-gray-800 transition-colors">
-                                            <i class="lni lni-chevron-down text-xs transition-transform duration-200" :class="expandedTocId === item.id ? 'rotate-180' : ''"></i>
-                                        </button>
-                                    </div>
-                                    <div v-show="expandedTocId === item.id" class="mt-1 space-y-1">
-                                        <a v-for="child in item.children" :key="child.id" @click.prevent="scrollToHeader(child.id)" :href="'#'+child.id"
-                                           class="block text-xs text-slate-500 dark:text-gray-500 hover:text-blue-600 dark:hover:text-blue-400 transition-colors truncate pl-8 py-1 ml-px">
-                                           {{ child.title }}
-                                        </a>
-                                    </div>
-                                </div>
-                             </template>
-                        </nav>
-                    </div>
-                </aside>
-            </div>
-        </div>
-        <div v-if="sidebarOpen" @click="toggleSidebar" class="md:hidden fixed inset-0 bg-gray-900 bg-opacity-20 z-10 backdrop-blur-sm"></div>
-    </div>
-
-    <script>
-        const { createApp, ref, computed, watch, onMounted, nextTick } = Vue;
-        const { createRouter, createWebHashHistory, useRoute } = VueRouter;
-
-        const SidebarItem = {
-            name: 'SidebarItem',
-            props: ['item'],
-            setup(props) {
-                const route = useRoute();
-                const isOpen = ref(false);
-                const hasActiveChild = (item, currentPath) => {
-                    if (item.slug === currentPath) return true;
-                    if (item.children) return item.children.some(child => hasActiveChild(child, currentPath));
-                    return false;
-                };
-                watch(() => route.path, (newPath) => {
-                    if (props.item.is_folder && hasActiveChild(props.item, newPath)) isOpen.value = true;
-                }, { immediate: true });
-                return { isOpen, toggle: () => isOpen.value = !isOpen.value };
-            },
-            template: '<div class="mb-1 select-none">' +
-                '<div v-if="item.is_folder">' +
-                    '<button @click="toggle" class="w-full flex items-center justify-between px-2 py-1.5 text-sm font-semibold text-slate-700 dark:text-gray-300 hover:bg-gray-100 dark:hover:bg-gray-800 rounded-md transition-colors">' +
-                        '<div class="flex items-center"><i class="lni lni-folder mr-2 text-slate-400"></i><span>{{ item.title }}</span></div>' +
-                        '<i class="lni lni-chevron-right text-xs text-gray-400 transform transition-transform duration-200" :class="isOpen ? \'rotate-90\' : \'\'"></i>' +
-                    '</button>' +
-                    '<div v-if="isOpen" class="pl-2 mt-1 ml-2 border-l border-gray-200 dark:border-gray-700 space-y-0.5"><sidebar-item v-for="child in item.children" :key="child.title" :item="child"></sidebar-item></div>' +
-                '</div>' +
-                '<router-link v-else :to="item.slug" class="block px-3 py-1.5 rounded-md text-sm font-medium transition-colors duration-200 flex items-center" :class="$route.path === item.slug ? \'bg-white dark:bg-gray-800 text-blue-600 dark:text-blue-400 shadow-sm border border-gray-100 dark:border-gray-700\' : \'text-slate-600 dark:text-gray-400 hover:bg-gray-100 dark:hover:bg-gray-800 hover:text-slate-900 dark:hover:text-gray-200\'">{{ item.title }}</router-link>' +
-            '</div>'
-        };
-
-        const PageView = {
-            props: ['data', 'flatMenu'],
-            setup(props) {
-                const route = useRoute();
-                const processedContent = computed(() => {
-                    if (!props.data.content) return '';
-                    let html = props.data.content;
-                    const icons = {
-                        note: '<i class="lni lni-notepad"></i>',
-                        tip: '<i class="lni lni-bulb"></i>',
-                        important: '<i class="lni lni-bookmark"></i>',
-                        warning: '<i class="lni lni-warning"></i>',
-                        caution: '<i class="lni lni-ban"></i>'
-                    };
-                    const regex = /<blockquote>\s*<p>\s*\[!(NOTE|TIP|IMPORTANT|WARNING|CAUTION)\]\s*(.*?)<\/p>\s*(.*?)<\/blockquote>/gs;
-                    html = html.replace(regex, (match, type, titleLine, body) => {
-                        const typeLower = type.toLowerCase();
-                        const title = titleLine.trim() || type;
-                        const icon = icons[typeLower] || icons.note;
-                        return '<div class="admonition admonition-' + typeLower + '"><div class="admonition-title">' + icon + ' ' + title + '</div><div>' + body + '</div></div>';
-                    });
-                    return html;
-                });
-
-                const navLinks = computed(() => {
-                    if (!props.flatMenu || props.flatMenu.length === 0) return { prev: null, next: null };
-                    const currentIndex = props.flatMenu.findIndex(p => p.slug === route.path);
-                    if (currentIndex === -1) return { prev: null, next: null };
-                    
-                    // Logic updated: Home (index 0) gets no Prev. Last gets no Next.
-                    return {
-                        prev: currentIndex > 0 ? props.flatMenu[currentIndex - 1] : null,
-                        next: currentIndex < props.flatMenu.length - 1 ? props.flatMenu[currentIndex + 1] : null
-                    };
-                });
-
-                onMounted(() => { injectCopyButtons(); resolveTransclusions(); });
-                watch(() => props.data.content, () => nextTick(() => { injectCopyButtons(); resolveTransclusions(); }));
-
-                function injectCopyButtons() {
-                    document.querySelectorAll('pre').forEach(pre => {
-                        if (pre.parentNode.classList.contains('code-wrapper')) return;
-                        const wrapper = document.createElement('div');
-                        wrapper.className = 'code-wrapper';
-                        pre.parentNode.insertBefore(wrapper, pre);
-                        wrapper.appendChild(pre);
-                        const btn = document.createElement('button');
-                        btn.className = 'copy-btn';
-                        btn.textContent = 'Copy';
-                        btn.onclick = () => {
-                            navigator.clipboard.writeText(pre.innerText).then(() => {
-                                btn.textContent = 'Copied!';
-                                setTimeout(() => btn.textContent = 'Copy', 2000);
-                            });
-                        };
-                        wrapper.appendChild(btn);
-                    });
-                }
-                function resolveTransclusions() {
-                     const placeholders = document.querySelectorAll('.transclusion-placeholder');
-                    if (placeholders.length === 0) return;
-                    placeholders.forEach(el => {
-                        const slug = el.getAttribute('data-slug');
-                        const id = el.getAttribute('data-id');
-                        if (window.siteData && window.siteData.pages[slug]) {
-                            const rawHtml = window.siteData.pages[slug].content;
-                            const tempDiv = document.createElement('div');
-                            tempDiv.innerHTML = rawHtml;
-                            const startNode = tempDiv.querySelector('#' + id);
-                            if (startNode) {
-                                let content = '';
-                                const startLevel = parseInt(startNode.tagName.substring(1));
-                                let currentNode = startNode;
-                                content += currentNode.outerHTML; 
-                                while (currentNode.nextElementSibling) {
-                                    currentNode = currentNode.nextElementSibling;
-                                    const tagName = currentNode.tagName;
-                                    if (/^H[1-6]$/.test(tagName)) {
-                                        const currentLevel = parseInt(tagName.substring(1));
-                                        if (currentLevel <= startLevel) break;
-                                    }
-                                    content += currentNode.outerHTML;
-                                }
-                                el.innerHTML = content;
-                                el.classList.remove('animate-pulse');
-                                el.classList.add('bg-opacity-50');
-                            } else {
-                                el.innerHTML = '<span class="text-red-500 text-sm">Error: Section #'+id+' not found in '+slug+'</span>';
-                            }
-                        } else {
-                            el.innerHTML = '<span class="text-red-500 text-sm">Error: Page '+slug+' not found</span>';
-                        }
-                    });
-                }
-
-                return { processedContent, navLinks };
-            },
-            template: '<div>' +
-                '<h1 class="text-4xl font-bold text-slate-900 dark:text-white mb-4 tracking-tight">{{ data.title }}</h1>' +
-                '<div class="flex items-center flex-wrap gap-4 text-sm text-slate-500 dark:text-gray-400 mb-8 pb-6 border-b border-gray-100 dark:border-gray-800">' +
-                    '<span v-if="data.category" class="inline-flex items-center px-2.5 py-0.5 rounded-full text-xs font-medium bg-blue-50 dark:bg-blue-900 text-blue-700 dark:text-blue-200 border border-blue-100 dark:border-blue-800">{{ data.category }}</span>' +
-                    '<div v-if="data.published || data.updated" class="flex items-center space-x-3 ml-1">' +
-                        '<span v-if="data.published">Published: <span class="text-slate-700 dark:text-gray-300 font-medium">{{ data.published }}</span></span>' +
-                        '<span v-if="data.published && data.updated" class="text-gray-300 dark:text-gray-600">•</span>' +
-                        '<span v-if="data.updated">Updated: <span class="text-slate-700 dark:text-gray-300 font-medium">{{ data.updated }}</span></span>' +
-                    '</div>' +
-                '</div>' +
-                '<article class="prose prose-slate dark:prose-invert prose-lg max-w-none prose-headings:font-semibold prose-a:text-blue-600 prose-a:no-underline hover:prose-a:underline" v-html="processedContent"></article>' +
-                '<div class="mt-16 pt-8 border-t border-gray-100 dark:border-gray-800 flex flex-col md:flex-row justify-between gap-4">' +
-                    '<div v-if="navLinks.prev">' +
-                        '<div class="text-xs text-gray-500 mb-1">Previous</div>' +
-                        '<router-link :to="navLinks.prev.slug" class="text-blue-600 dark:text-blue-400 font-medium transition-colors hover:text-blue-800 dark:hover:text-blue-300 flex items-center">' +
-                            '<i class="lni lni-arrow-left mr-2"></i> {{ navLinks.prev.title }}' +
-                        '</router-link>' +
-                    '</div>' +
-                    '<div v-else class="flex-1"></div>' +
-                    '<div v-if="navLinks.next" class="text-right">' +
-                        '<div class="text-xs text-gray-500 mb-1">Next</div>' +
-                        '<router-link :to="navLinks.next.slug" class="text-blue-600 dark:text-blue-400 font-medium transition-colors hover:text-blue-800 dark:hover:text-blue-300 flex items-center justify-end">' +
-                            '{{ navLinks.next.title }} <i class="lni lni-arrow-right ml-2"></i>' +
-                        '</router-link>' +
-                    '</div>' +
-                '</div>' +
-            '</div>'
-        };
-
-        const SitemapView = {
-            props: ['menu'],
-            template: '<div><h1 class="text-4xl font-bold mb-8 dark:text-white">Site Index</h1><div class="grid grid-cols-1 md:grid-cols-2 gap-8"><div v-for="item in menu" :key="item.title"><h3 class="font-bold text-lg mb-2 text-slate-800 dark:text-gray-200">{{ item.title }}</h3><ul class="space-y-1"><li v-if="!item.is_folder"><router-link :to="item.slug" class="text-blue-600 dark:text-blue-400 hover:text-blue-800 dark:hover:text-blue-300">{{ item.title }}</router-link></li><li v-else v-for="child in item.children" :key="child.title" class="ml-4 list-disc marker:text-slate-300 dark:marker:text-gray-600"><router-link :to="child.slug" class="text-blue-600 dark:text-blue-400 hover:text-blue-800 dark:hover:text-blue-300">{{ child.title }}</router-link></li></ul></div></div></div>'
-        };
-
-        const app = createApp({
-            setup() {
-                const loading = ref(true);
-                const menu = ref([]);
-                const flatMenu = ref([]);
-                const sidebarOpen = ref(window.innerWidth > 1024);
-                const route = useRoute();
-                const mainScroll = ref(null);
-                const isDark = ref(localStorage.getItem('theme') === 'dark');
-                const filteredMenu = computed(() => { return menu.value.filter(item => item.slug !== '/'); });
-                
-                // TOC Logic
-                const expandedTocId = ref(null);
-                
-                const toggleDarkMode = () => {
-                    isDark.value = !isDark.value;
-                    if (isDark.value) {
-                        document.documentElement.classList.add('dark');
-                        localStorage.setItem('theme', 'dark');
-                    } else {
-                        document.documentElement.classList.remove('dark');
-                        localStorage.setItem('theme', 'light');
-                    }
-                };
-                if (isDark.value) document.documentElement.classList.add('dark');
-                
-                const searchQuery = ref('');
-                const allPagesList = ref([]);
-                const filteredPages = computed(() => {
-                    if (!searchQuery.value) return [];
-                    const q = searchQuery.value.toLowerCase();
-                    return allPagesList.value.filter(p => p.title.toLowerCase().includes(q));
-                });
-
-                const flattenMenuTree = (items) => {
-                    let flat = [];
-                    items.forEach(item => {
-                        if (!item.is_folder) flat.push(item);
-                        if (item.children) flat = flat.concat(flattenMenuTree(item.children));
-                    });
-                    return flat;
-                };
-                
-                fetch('db.json').then(res => res.json()).then(data => {
-                    window.siteData = data;
-                    menu.value = data.menu;
-                    flatMenu.value = flattenMenuTree(data.menu);
-                    allPagesList.value = Object.keys(data.pages).map(slug => ({
-                        slug, ...data.pages[slug]
-                    }));
-                    loading.value = false;
-                });
-                
-                const currentPage = computed(() => {
-                    if (loading.value || !window.siteData) return { toc: [] };
-                    return window.siteData.pages[route.path] || { title: '404', content: "<h1 class='text-red-500'>404 Not Found</h1>", toc: [] };
-                });
-                
-                const nestedToc = computed(() => {
-                    const raw = currentPage.value.toc || [];
-                    const res = [];
-                    let currentParent = null;
-                    raw.forEach(item => {
-                        if (item.level <= 2) {
-                            currentParent = { ...item, children: [] };
-                            res.push(currentParent);
-                        } else {
-                            if (currentParent) {
-                                currentParent.children.push(item);
-                            } else {
-                                currentParent = { ...item, children: [] };
-                                res.push(currentParent);
-                            }
-                        }
-                    });
-                    return res;
-                });
-                
-                watch(() => currentPage.value, (page) => {
-                    document.title = page.title ? page.title : 'Docs';
-                    const metaDesc = document.querySelector('meta[name="description"]');
-                    if (metaDesc) metaDesc.setAttribute("content", page.description || "Documentation");
-                });
-                
-                watch(() => route.path, () => {
-                    if(mainScroll.value) mainScroll.value.scrollTop = 0;
-                    if(window.innerWidth < 1024) sidebarOpen.value = false;
-                    expandedTocId.value = null;
-                });
-                
-                const toggleSidebar = () => sidebarOpen.value = !sidebarOpen.value;
-                const scrollToHeader = (id) => {
-                    const el = document.getElementById(id);
-                    if(el) el.scrollIntoView({ behavior: 'smooth', block: 'start' });
-                };
-                
-                const toggleToc = (id, forceOpen = false) => {
-                    if (forceOpen) {
-                        expandedTocId.value = id;
-                    } else {
-                        expandedTocId.value = expandedTocId.value === id ? null : id;
-                    }
-                };
-                
-                return { loading, menu, flatMenu, filteredMenu, currentPage, sidebarOpen, toggleSidebar, mainScroll, scrollToHeader, isDark, toggleDarkMode, searchQuery, filteredPages, nestedToc, expandedTocId, toggleToc };
-            }
-        });
-
-        app.component('sidebar-item', SidebarItem);
-        app.use(createRouter({
-            history: createWebHashHistory(),
-            routes: [ { path: '/sitemap', component: SitemapView }, { path: '/:pathMatch(.*)*', component: PageView } ]
-        }));
-        app.mount('#app');
-    </script>
-</body>
-</html>`
-	return os.WriteFile(path, []byte(html), 0644)
-}
\ No newline at end of file
+// defaultTemplates are the built-in templates of the app shell, with
+// shell.html as the entry point. A file of the same name in
+// cfg.TemplatesDir replaces one of them.
+//
+//go:embed templates/*.html
+var defaultTemplates embed.FS
+
+// ShellData is what the app shell templates are executed with
+type ShellData struct {
+	Title         string
+	Description   string
+	Config        template.JS   // shellConfig of the app script
+	ThemeHead     template.HTML // theme font and CSS variables
+	FeedLinks     template.HTML
+	PrintStyle    string        // URL of the print stylesheet
+	PrerenderHead template.HTML // markers filled per page by prerenderPage
+	PrerenderBody template.HTML
+	Partials      map[string]template.HTML // by injection point, see partialNames
+}
+
+// LoadShellTemplates parses the built-in templates, then the .html files of
+// dir over them. They use [[ ]] as delimiters, leaving {{ }} to Vue.
+func LoadShellTemplates(dir string) (*template.Template, error) {
+	t, err := template.New("").Delims("[[", "]]").ParseFS(defaultTemplates, "templates/*.html")
+	if err != nil {
+		return nil, err
+	}
+	if dir == "" {
+		return t, nil
+	}
+	overrides, _ := filepath.Glob(filepath.Join(dir, "*.html"))
+	if len(overrides) == 0 {
+		return t, nil
+	}
+	return t.ParseFiles(overrides...)
+}
+
+// RenderAppShell returns the single page app shell from the templates,
+// filling the injection points with the given partials and linking the
+// feeds for autodiscovery. The prerender markers are left for
+// prerenderPage, and data files are fetched under their names in assets.
+func RenderAppShell(partials map[string]string, feeds []Feed, assets AssetManifest) (string, error) {
+	tmpl, err := LoadShellTemplates(cfg.TemplatesDir)
+	if err != nil {
+		return "", err
+	}
+	taxonomies := []map[string]string{}
+	for _, name := range sortedKeys(cfg.Taxonomies) {
+		_, label := taxonomyNames(name)
+		taxonomies = append(taxonomies, map[string]string{"name": name, "label": label, "slug": taxonomySlug(name)})
+	}
+	files := make(map[string]string)
+	for _, name := range []string{"db.json", "search.json", "palette.json", "catalog.json", "graph.json"} {
+		if hashed, ok := assets[name]; ok {
+			files[name] = hashed
+		}
+	}
+	config, _ := json.Marshal(map[string]interface{}{
+		"routing":       cfg.URLRouting,
+		"trailingSlash": cfg.URLTrailingSlash,
+		"indexFile":     cfg.URLIndexFile,
+		"base":          basePath(),
+		"siteURL":       strings.TrimSuffix(cfg.BaseURL, "/"),
+		"prerender":     cfg.Prerender,
+		"files":         files,
+		"taxonomies":    taxonomies,
+		"title":         cfg.Title,
+		"darkMode":      cfg.Theme.DarkMode,
+	})
+	data := ShellData{
+		Title:         cfg.Title,
+		Description:   cfg.Description,
+		Config:        template.JS(config),
+		ThemeHead:     template.HTML(themeHead()),
+		FeedLinks:     template.HTML(feedLinks(headFeeds(feeds, "", PageData{}))),
+		PrintStyle:    basePath() + "/" + assets.Name(PrintStyleFile),
+		PrerenderHead: template.HTML(prerenderHeadMarker),
+		PrerenderBody: template.HTML(prerenderBodyMarker),
+		Partials:      make(map[string]template.HTML, len(partialNames)),
+	}
+	for _, name := range partialNames {
+		data.Partials[name] = template.HTML(partials[name])
+	}
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, "shell.html", data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// WriteAppShell writes the shell without page content
+func WriteAppShell(path, shell string) error {
+	return os.WriteFile(path, []byte(minifyHTML(prerenderPage(shell, "", nil, nil, nil))), 0644)
+}
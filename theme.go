@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// cssVariableName matches the custom property names allowed in theme.variables
+var cssVariableName = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// cssVariables returns the CSS custom properties of the theme by name
+func (t ThemeConfig) cssVariables() map[string]string {
+	vars := make(map[string]string, len(t.Variables)+8)
+	for name, value := range t.Variables {
+		vars[name] = value
+	}
+	for name, value := range map[string]string{
+		"color-primary":      t.PrimaryColor,
+		"color-primary-dark": t.PrimaryColorDark,
+		"font-sans":          t.FontFamily,
+		"font-mono":          t.MonoFont,
+		"radius":             t.Radius,
+		"sidebar-width":      t.SidebarWidth,
+		"content-width":      t.ContentWidth,
+	} {
+		if value != "" {
+			vars[name] = value
+		}
+	}
+	return vars
+}
+
+func (t ThemeConfig) validate() error {
+	for name, value := range t.cssVariables() {
+		if !cssVariableName.MatchString(name) {
+			return fmt.Errorf("theme.variables: invalid name %q, use lowercase letters, digits and dashes", name)
+		}
+		if strings.ContainsAny(value, ";{}<>") {
+			return fmt.Errorf("theme: invalid value %q for --%s", value, name)
+		}
+	}
+	return nil
+}
+
+// themeHead returns the font stylesheet and the :root rule declaring the
+// theme's custom properties, for the <head> of the shell.
+func themeHead() string {
+	var buf strings.Builder
+	if cfg.Theme.FontURL != "" {
+		buf.WriteString(fmt.Sprintf("    <link href=\"%s\" rel=\"stylesheet\">\n", html.EscapeString(cfg.Theme.FontURL)))
+	}
+	buf.WriteString("    <style>\n        :root {")
+	vars := cfg.Theme.cssVariables()
+	for _, name := range sortedKeys(vars) {
+		buf.WriteString(fmt.Sprintf(" --%s: %s;", name, vars[name]))
+	}
+	buf.WriteString(" }\n    </style>\n")
+	return buf.String()
+}
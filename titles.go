@@ -0,0 +1,47 @@
+package main
+
+import (
+	"strings"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+// titleAcronyms are always written in upper case in derived titles
+var titleAcronyms = map[string]bool{
+	"API": true, "CLI": true, "CSS": true, "DNS": true, "FAQ": true, "FTP": true, "GUI": true,
+	"HTML": true, "HTTP": true, "HTTPS": true, "ID": true, "IP": true, "JSON": true, "RSS": true,
+	"SDK": true, "SQL": true, "SSH": true, "SSL": true, "TLS": true, "UI": true, "URL": true,
+	"UUID": true, "VPN": true, "VPS": true, "XML": true, "YAML": true,
+}
+
+// titleSmallWords are kept lower case unless first or last, per language
+var titleSmallWords = map[string]map[string]bool{
+	"en": {"a": true, "an": true, "and": true, "as": true, "at": true, "but": true, "by": true, "for": true,
+		"in": true, "nor": true, "of": true, "on": true, "or": true, "the": true, "to": true, "vs": true, "with": true},
+	"de": {"und": true, "oder": true, "der": true, "die": true, "das": true, "mit": true, "von": true, "zu": true},
+	"fr": {"et": true, "ou": true, "le": true, "la": true, "les": true, "de": true, "des": true, "du": true, "en": true},
+}
+
+// titleCase turns a file name like "faq-and-tips" into a display title
+// ("FAQ and Tips"), honoring acronyms and the small words of TitleLocale.
+func titleCase(name string) string {
+	tag := language.Make(cfg.TitleLocale)
+	base, _ := tag.Base()
+	small := titleSmallWords[base.String()]
+	caser := cases.Title(tag, cases.NoLower)
+
+	words := strings.Fields(strings.NewReplacer("-", " ", "_", " ").Replace(name))
+	for i, w := range words {
+		lower := cases.Lower(tag).String(w)
+		switch {
+		case titleAcronyms[strings.ToUpper(w)]:
+			words[i] = strings.ToUpper(w)
+		case i > 0 && i < len(words)-1 && small[lower]:
+			words[i] = lower
+		default:
+			words[i] = caser.String(w)
+		}
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// The placeholder processCustomSyntax leaves for a {{ref:slug#id}} tag, on a
+// line of its own wrapped in a paragraph by the markdown renderer
+var (
+	refPlaceholder      = `<div class="transclusion-placeholder[^"]*" data-slug="([^"]*)" data-id="([^"]*)">.*?</div>`
+	refPlaceholderRegex = regexp.MustCompile(`<p>` + refPlaceholder + `</p>\n?|` + refPlaceholder)
+	headingTagRegex     = regexp.MustCompile(`<h([1-6])\b[^>]*>`)
+)
+
+// sectionHTML cuts the section of content starting at the heading with id
+// and running up to the next heading of the same or a higher level. An
+// empty id gives the whole content.
+func sectionHTML(content, id string) (string, bool) {
+	if id == "" {
+		return content, true
+	}
+	attr := ` id="` + html.EscapeString(id) + `"`
+	tags := headingTagRegex.FindAllStringSubmatchIndex(content, -1)
+	for i, loc := range tags {
+		if !strings.Contains(content[loc[0]:loc[1]], attr) {
+			continue
+		}
+		level := content[loc[2]:loc[3]]
+		for _, next := range tags[i+1:] {
+			if content[next[2]:next[3]] <= level {
+				return content[loc[0]:next[0]], true
+			}
+		}
+		return content[loc[0]:], true
+	}
+	return "", false
+}
+
+// transclusions inlines the sections referenced by pages and snippets,
+// resolving the refs of the referenced content first
+type transclusions struct {
+	site     *SiteData
+	resolved map[string]string // slug -> content with its refs inlined
+	problems map[string][]string
+}
+
+// source returns the content of a page or snippet, following redirects
+func (t *transclusions) source(slug string) (string, string, bool) {
+	if to, ok := t.site.Redirects[slug]; ok {
+		slug = to
+	}
+	if page, ok := t.site.Pages[slug]; ok {
+		return slug, page.Content, true
+	}
+	content, ok := t.site.Snippets[slug]
+	return slug, content, ok
+}
+
+// expand returns the content at slug with its refs inlined; stack holds the
+// pages being expanded, to catch refs leading back to one of them
+func (t *transclusions) expand(slug string, stack []string) string {
+	if content, ok := t.resolved[slug]; ok {
+		return content
+	}
+	_, content, _ := t.source(slug)
+	stack = append(stack, slug)
+	content = refPlaceholderRegex.ReplaceAllStringFunc(content, func(m string) string {
+		parts := refPlaceholderRegex.FindStringSubmatch(m)
+		target, id := parts[1]+parts[3], html.UnescapeString(parts[2]+parts[4])
+		fail := func(problem, message string) string {
+			t.problems[slug] = append(t.problems[slug], problem)
+			return `<span class="text-red-500 text-sm">Error: ` + html.EscapeString(message) + `</span>`
+		}
+		target, _, ok := t.source(target)
+		if !ok {
+			return fail(fmt.Sprintf("{{ref:%s#%s}}: no such page", strings.TrimPrefix(target, "/"), id), "Page "+target+" not found")
+		}
+		for i, s := range stack {
+			if s == target {
+				cycle := strings.Join(append(stack[i:], target), " -> ")
+				return fail("transclusion cycle "+cycle, "Circular reference "+cycle)
+			}
+		}
+		section, ok := sectionHTML(t.expand(target, stack), id)
+		if !ok {
+			return fail(fmt.Sprintf("{{ref:%s#%s}}: %s has no #%s", strings.TrimPrefix(target, "/"), id, target, id), "Section #"+id+" not found in "+target)
+		}
+		return fmt.Sprintf("<div class=\"transclusion p-4 border-l-4 border-purple-500 bg-gray-50 dark:bg-gray-800 my-4\" data-slug=\"%s\" data-id=\"%s\">\n%s</div>\n",
+			html.EscapeString(target), html.EscapeString(id), section)
+	})
+	t.resolved[slug] = content
+	return content
+}
+
+// ResolveTransclusions replaces the {{ref:slug#id}} placeholders of pages
+// and snippets with the referenced section, reporting missing pages and
+// sections and refs that lead back to the page. It returns the number of
+// broken refs.
+func ResolveTransclusions(site *SiteData) int {
+	t := &transclusions{site: site, resolved: make(map[string]string), problems: make(map[string][]string)}
+	for _, slug := range sortedKeys(site.Pages) {
+		t.expand(slug, nil)
+	}
+	for _, slug := range sortedKeys(site.Snippets) {
+		t.expand(slug, nil)
+	}
+	for slug, content := range t.resolved {
+		if page, ok := site.Pages[slug]; ok {
+			page.Content = content
+			site.Pages[slug] = page
+		} else {
+			site.Snippets[slug] = content
+		}
+	}
+	count := 0
+	for _, slug := range sortedKeys(t.problems) {
+		for _, p := range t.problems[slug] {
+			fmt.Printf("Warning: %s: %s\n", slug, p)
+		}
+		count += len(t.problems[slug])
+	}
+	return count
+}
@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestSectionHTML(t *testing.T) {
+	content := `<p>intro</p>` +
+		`<h2 id="install">Install</h2><p>a</p>` +
+		`<h3 id="linux">Linux</h3><p>b</p>` +
+		`<h4 id="debian">Debian</h4><p>c</p>` +
+		`<h3 id="macos">macOS</h3><p>d</p>` +
+		`<h2 id="q&amp;a">Q&amp;A</h2><p>e</p>`
+
+	tests := []struct {
+		id, want string
+		ok       bool
+	}{
+		{"", content, true},
+		{"install", `<h2 id="install">Install</h2><p>a</p><h3 id="linux">Linux</h3><p>b</p><h4 id="debian">Debian</h4><p>c</p><h3 id="macos">macOS</h3><p>d</p>`, true},
+		{"linux", `<h3 id="linux">Linux</h3><p>b</p><h4 id="debian">Debian</h4><p>c</p>`, true},
+		{"debian", `<h4 id="debian">Debian</h4><p>c</p>`, true},
+		{"macos", `<h3 id="macos">macOS</h3><p>d</p>`, true},
+		{"q&a", `<h2 id="q&amp;a">Q&amp;A</h2><p>e</p>`, true},
+		{"lin", "", false},
+		{"missing", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := sectionHTML(content, tt.id)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("sectionHTML(%q) = %q, %v, want %q, %v", tt.id, got, ok, tt.want, tt.ok)
+		}
+	}
+}
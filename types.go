@@ -1,42 +1,138 @@
 package main
 
-// Configuration constants
-const (
-	InputDir  = "./content"
-	OutputDir = "./public"
-	BaseURL   = "https://mysite.com"
-)
+import "time"
 
 // SiteData represents the entire database of the site
 type SiteData struct {
-	Pages map[string]PageData `json:"pages"`
-	Menu  []*MenuItem         `json:"menu"`
+	Version  int                 `json:"version"` // layout of this file, see Config.APIVersion
+	Pages    map[string]PageData `json:"pages"`
+	Menu     []*MenuItem         `json:"menu"`
+	Snippets map[string]string   `json:"snippets,omitempty"` // rendered _snippets, for transclusion
+	SlugMap  map[string]string   `json:"slug_map,omitempty"` // original-case slug -> normalized slug
+
+	Taxonomies map[string]map[string]int          `json:"taxonomies"`           // taxonomy -> term -> page count
+	Tags       map[string]*TermListing            `json:"tags,omitempty"`       // tag slug -> tagged pages
+	Categories map[string]*TermListing            `json:"categories,omitempty"` // category slug -> its pages
+	Terms      map[string]map[string]*TermListing `json:"terms,omitempty"`      // custom taxonomy -> term slug -> its pages
+	Posts      *PostList                          `json:"posts,omitempty"`      // paginated posts section
+	Redirects  map[string]string                  `json:"redirects,omitempty"`  // old slug -> current slug
 }
 
 // PageData represents a single page's content and metadata
 type PageData struct {
-	Title       string     `json:"title"`
-	Content     string     `json:"content"`
-	TOC         []TOCEntry `json:"toc"`
-	Published   string     `json:"published"`
-	Updated     string     `json:"updated"`
-	Category    string     `json:"category"`
-	Description string     `json:"description"`
-	Weight      int        `json:"weight"`
+	Title       string              `json:"title"`
+	Content     string              `json:"content"`
+	TOC         []TOCEntry          `json:"toc"`
+	Published   string              `json:"published"`
+	Updated     string              `json:"updated"`
+	PublishedAt time.Time           `json:"-"` // zero when missing or invalid
+	UpdatedAt   time.Time           `json:"-"`
+	ModTime     time.Time           `json:"-"` // of the source file, zero for generated pages
+	Category    string              `json:"category"`
+	Tags        []string            `json:"tags,omitempty"`
+	Terms       map[string][]string `json:"terms,omitempty"` // custom taxonomy -> terms
+	Description string              `json:"description"`
+	Weight      int                 `json:"weight"`
+	Layout      string              `json:"layout,omitempty"`
+	Draft       bool                `json:"draft,omitempty"`      // only present in builds with drafts
+	MenuTitle   string              `json:"menu_title,omitempty"` // short sidebar label, defaults to Title
+	HideTOC     bool                `json:"hide_toc,omitempty"`   // frontmatter `toc: false`
+	HideMeta    bool                `json:"hide_meta,omitempty"`  // frontmatter `show_meta: false`
+	Related     []PageLink          `json:"related,omitempty"`
+	Backlinks   []PageLink          `json:"backlinks,omitempty"`   // content pages linking here
+	Breadcrumbs []PageLink          `json:"breadcrumbs,omitempty"` // Home and the folders above the page; Slug is empty for folders without a page
+	Prev        *PageLink           `json:"prev,omitempty"`        // neighbours in the folder, or by date for posts
+	Next        *PageLink           `json:"next,omitempty"`
+	Keywords    []string            `json:"keywords,omitempty"`
+	Meta        map[string]string   `json:"meta,omitempty"`      // extra <meta> tags from frontmatter `meta:`
+	Changefreq  string              `json:"-"`                   // sitemap override from frontmatter
+	NoIndex     bool                `json:"noindex,omitempty"`   // hidden from search engines, sitemaps and feeds
+	Canonical   string              `json:"canonical,omitempty"` // original URL of syndicated or duplicate content
+	Image       string              `json:"image,omitempty"`     // absolute URL of the social preview image
+	Author      string              `json:"author,omitempty"`
+	JSONLD      string              `json:"-"`                     // schema.org structured data for the static HTML outputs
+	Extra       map[string]any      `json:"extra,omitempty"`       // frontmatter keys without a field of their own
+	Type        string              `json:"type,omitempty"`        // content type: frontmatter `type`, or the section
+	Data        interface{}         `json:"data,omitempty"`        // frontmatter decoded into the registered type
+	ListPage    int                 `json:"list_page,omitempty"`   // number of the posts listing page shown below the content
+	Catalog     bool                `json:"catalog,omitempty"`     // the "All guides" page, filtered over catalog.json in the shell
+	Aliases     []string            `json:"-"`                     // old paths redirecting here (`aliases`, `previously`)
+	Short       string              `json:"short,omitempty"`       // short link redirecting here, from frontmatter `short`
+	Owners      []string            `json:"owners,omitempty"`      // maintainers from frontmatter `owner` or CODEOWNERS
+	ReviewedBy  string              `json:"reviewed_by,omitempty"` // last reviewer, from frontmatter `reviewed_by`
+	ReviewDue   string              `json:"review_due,omitempty"`  // next review date, from frontmatter `review_due`
+	ReviewDueAt time.Time           `json:"-"`
+	EditURL     string              `json:"edit_url,omitempty"` // where to edit the source, see Config.EditURL
+	QRCode      string              `json:"qr_code,omitempty"`  // QR code PNG of the page URL, see Config.QRCodes
+}
+
+// frontmatterKeys are the frontmatter keys mapped to PageData fields;
+// all others are passed through in Extra.
+var frontmatterKeys = map[string]bool{
+	"title": true, "menu_title": true, "description": true, "published on": true, "updated on": true,
+	"category": true, "tags": true, "weight": true, "order": true, "layout": true, "toc": true, "show_meta": true,
+	"meta": true, "draft": true, "changefreq": true, "noindex": true, "canonical": true, "type": true, "image": true, "author": true,
+	"aliases": true, "previously": true, "owner": true, "hero": true, "features": true, "short": true,
+	"locked": true, "locked_approved_by": true, "reviewed_by": true, "review_due": true, "cascade": true,
+}
+
+// Indexed reports whether a page belongs in sitemaps and feeds: it is not
+// marked noindex and has no canonical URL elsewhere.
+func (p PageData) Indexed() bool {
+	return !p.NoIndex && p.Canonical == ""
+}
+
+// PageLink is a reference to another page
+type PageLink struct {
+	Title string `json:"title"`
+	Slug  string `json:"slug"`
 }
 
 // MenuItem represents a node in the navigation tree
 type MenuItem struct {
-	Title    string      `json:"title"`
-	Slug     string      `json:"slug"`
-	IsFolder bool        `json:"is_folder"`
-	Weight   int         `json:"weight"`
-	Children []*MenuItem `json:"children,omitempty"`
+	Title     string      `json:"title"`
+	Slug      string      `json:"slug"`
+	IsFolder  bool        `json:"is_folder"`
+	Weight    int         `json:"weight"`
+	URL       string      `json:"url,omitempty"`       // external link (nav.yaml and config entries)
+	Separator bool        `json:"separator,omitempty"` // divider line (nav.yaml and config entries)
+	Icon      string      `json:"icon,omitempty"`      // Lineicons name
+	Expand    string      `json:"expand,omitempty"`    // folder state: expanded, collapsed or active
+	Children  []*MenuItem `json:"children,omitempty"`
+
+	name  string               // file or folder name the node was created from
+	index map[string]*MenuItem // children by name, folders suffixed with "/"
+}
+
+// Initial states of a menu folder
+const (
+	ExpandAlways = "expanded"  // open by default
+	ExpandNever  = "collapsed" // closed until toggled, even when active
+	ExpandActive = "active"    // open only when it contains the current page (default)
+)
+
+// Page layouts understood by the app shell
+const (
+	LayoutDefault = ""        // docs page with sidebar, TOC and metadata
+	LayoutWide    = "wide"    // full-width content, no TOC column
+	LayoutLanding = "landing" // wide, without title, metadata and prev/next links
+	LayoutBare    = "bare"    // content only, no sidebar or header
+)
+
+// validLayout reports whether s is a known page layout
+func validLayout(s string) bool {
+	return s == LayoutDefault || s == LayoutWide || s == LayoutLanding || s == LayoutBare
+}
+
+// validExpand reports whether s is a known folder state (empty means default)
+func validExpand(s string) bool {
+	return s == "" || s == ExpandAlways || s == ExpandNever || s == ExpandActive
 }
 
 // TOCEntry represents a header in the Table of Contents
 type TOCEntry struct {
 	Title string `json:"title"`
+	HTML  string `json:"html,omitempty"` // title with code/emphasis markup, when it has any
 	ID    string `json:"id"`
 	Level int    `json:"level"`
 }
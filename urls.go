@@ -0,0 +1,175 @@
+package main
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Routing modes of the app shell
+const (
+	RoutingHash = "hash" // https://mysite.com/#/guide
+	RoutingPath = "path" // https://mysite.com/guide (needs host rewrites to index.html)
+)
+
+// basePath returns the path component of BaseURL without a trailing slash,
+// e.g. "/docs" for https://example.com/docs/
+func basePath() string {
+	u, err := url.Parse(cfg.BaseURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(u.Path, "/")
+}
+
+// styledPath applies the trailing slash / index.html style to a slug
+func styledPath(slug string) string {
+	if slug == "/" {
+		return "/"
+	}
+	switch {
+	case cfg.URLIndexFile:
+		return slug + "/index.html"
+	case cfg.URLTrailingSlash:
+		return slug + "/"
+	}
+	return slug
+}
+
+// pageHref returns the link to a page from within the site
+func pageHref(slug string) string {
+	if cfg.URLRouting == RoutingPath {
+		return basePath() + styledPath(slug)
+	}
+	return "#" + styledPath(slug)
+}
+
+// pageURL returns the absolute URL of a page
+func pageURL(slug string) string {
+	root := strings.TrimSuffix(cfg.BaseURL, "/")
+	if cfg.URLRouting == RoutingPath {
+		return root + styledPath(slug)
+	}
+	if slug == "/" {
+		return root + "/"
+	}
+	return root + "/#" + styledPath(slug)
+}
+
+// preferredURL returns the URL search engines should index for a page: the
+// static copy of a hash-routed page when prerendering, otherwise its app URL.
+func preferredURL(slug string) string {
+	if cfg.URLRouting == RoutingHash && cfg.Prerender && slug != "/" {
+		return strings.TrimSuffix(cfg.BaseURL, "/") + slug + "/"
+	}
+	return pageURL(slug)
+}
+
+// canonicalURL returns the canonical URL of a page: its `canonical`
+// frontmatter when set, otherwise preferredURL.
+func canonicalURL(slug string, page PageData) string {
+	if page.Canonical != "" {
+		return page.Canonical
+	}
+	return preferredURL(slug)
+}
+
+// urlAttrRegex matches link, image and media targets in rendered HTML
+var urlAttrRegex = regexp.MustCompile(`(\s(?:href|src|poster|srcset)=")([^"]*)(")`)
+
+// urlMetaKeys are the page meta tags holding a URL
+var urlMetaKeys = map[string]bool{
+	"og:url": true, "og:image": true, "og:image:url": true, "og:image:secure_url": true,
+	"og:video": true, "og:audio": true, "twitter:image": true,
+}
+
+// siteBase returns BaseURL as the root every relative URL resolves against
+func siteBase() *url.URL {
+	base, err := url.Parse(strings.TrimSuffix(cfg.BaseURL, "/") + "/")
+	if err != nil {
+		return nil
+	}
+	return base
+}
+
+// absolutizeHTML rewrites relative link, image and media URLs of the page
+// at slug against BaseURL, so content works outside the app: in feeds,
+// social previews or exports. In-page anchors point to the page.
+func absolutizeHTML(content, slug string) string {
+	base := siteBase()
+	if base == nil {
+		return content
+	}
+	return urlAttrRegex.ReplaceAllStringFunc(content, func(m string) string {
+		parts := urlAttrRegex.FindStringSubmatch(m)
+		if strings.HasSuffix(parts[1], `srcset="`) {
+			return parts[1] + absoluteSrcset(base, parts[2]) + parts[3]
+		}
+		if strings.HasPrefix(parts[2], "#") && !strings.HasPrefix(parts[2], "#/") {
+			return parts[1] + html.EscapeString(pageURL(slug)) + parts[2] + parts[3]
+		}
+		return parts[1] + absoluteURL(base, parts[2]) + parts[3]
+	})
+}
+
+// absoluteSrcset resolves every candidate of a srcset ("a.png 1x, b.png 2x")
+func absoluteSrcset(base *url.URL, srcset string) string {
+	candidates := strings.Split(srcset, ",")
+	for i, c := range candidates {
+		fields := strings.Fields(c)
+		if len(fields) == 0 {
+			continue
+		}
+		fields[0] = absoluteURL(base, fields[0])
+		candidates[i] = strings.Join(fields, " ")
+	}
+	return strings.Join(candidates, ", ")
+}
+
+// absolutizeMeta makes the URL-valued tags of a page's meta map absolute
+func absolutizeMeta(meta map[string]string) map[string]string {
+	for key, value := range meta {
+		if urlMetaKeys[key] {
+			meta[key] = absoluteLink(value)
+		}
+	}
+	return meta
+}
+
+// absoluteLink resolves a plain (not HTML-escaped) URL against BaseURL
+func absoluteLink(ref string) string {
+	base := siteBase()
+	if base == nil {
+		return ref
+	}
+	return html.UnescapeString(absoluteURL(base, html.EscapeString(ref)))
+}
+
+// absoluteURL resolves the HTML-escaped ref against base, leaving absolute
+// URLs and unparsable values alone. App routes ("#/guide") point into the
+// shell at base.
+func absoluteURL(base *url.URL, ref string) string {
+	if ref == "" || (strings.HasPrefix(ref, "#") && !strings.HasPrefix(ref, "#/")) {
+		return ref
+	}
+	u, err := url.Parse(html.UnescapeString(ref))
+	if err != nil || u.IsAbs() || u.Host != "" {
+		return ref
+	}
+	return html.EscapeString(base.ResolveReference(u).String())
+}
+
+// siteURL returns the absolute URL of a file in the output folder
+func siteURL(name string) string {
+	return strings.TrimSuffix(cfg.BaseURL, "/") + "/" + strings.TrimPrefix(name, "/")
+}
+
+// editURL returns the link to edit the source file at rel (slash-separated,
+// relative to cfg.InputDir), or "" when cfg.EditURL is not set
+func editURL(rel string) string {
+	if cfg.EditURL == "" {
+		return ""
+	}
+	return strings.ReplaceAll(cfg.EditURL, "{path}", (&url.URL{Path: rel}).EscapedPath())
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// WhatsNewSlug is the route of the generated "what's new" page
+const WhatsNewSlug = "/whats-new"
+
+// substantialChange is the share of words a page has to gain or lose for an
+// edit to count as an update; smaller edits (typos, links) are not listed
+const substantialChange = 0.1
+
+// ChangeHistory remembers across builds when each page was added and last
+// substantially changed, compared by content hash and word count.
+type ChangeHistory struct {
+	Builds int                   `json:"builds"` // number of builds recorded
+	Pages  map[string]pageChange `json:"pages"`
+
+	path string
+}
+
+type pageChange struct {
+	Hash         string    `json:"hash"`
+	Words        int       `json:"words"`
+	Added        time.Time `json:"added,omitempty"`
+	AddedBuild   int       `json:"added_build,omitempty"` // 0 when the page predates the history
+	Updated      time.Time `json:"updated,omitempty"`
+	UpdatedBuild int       `json:"updated_build,omitempty"`
+}
+
+// LoadChangeHistory reads the history at path; an empty path disables it
+func LoadChangeHistory(path string) *ChangeHistory {
+	if path == "" {
+		return nil
+	}
+	h := &ChangeHistory{Pages: make(map[string]pageChange), path: path}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return h
+	}
+	if err := json.Unmarshal(data, h); err != nil {
+		fmt.Println("Warning: ignoring corrupt change history:", err)
+		h.Builds, h.Pages = 0, make(map[string]pageChange)
+	}
+	return h
+}
+
+// Record compares the pages with the previous build. On the first build
+// every page is taken as existing already, so nothing shows up as new.
+func (h *ChangeHistory) Record(pages map[string]PageData, now time.Time) {
+	first := h.Builds == 0
+	h.Builds++
+	seen := make(map[string]bool, len(pages))
+	for slug, page := range pages {
+		seen[slug] = true
+		hash, words := hashBytes([]byte(page.Content)), wordCount(page.Content)
+		prev, ok := h.Pages[slug]
+		switch {
+		case !ok && !first:
+			prev = pageChange{Added: now, AddedBuild: h.Builds}
+		case ok && prev.Hash != hash && changedWords(prev.Words, words) >= substantialChange:
+			prev.Updated, prev.UpdatedBuild = now, h.Builds
+		}
+		prev.Hash, prev.Words = hash, words
+		h.Pages[slug] = prev
+	}
+	for slug := range h.Pages {
+		if !seen[slug] {
+			delete(h.Pages, slug)
+		}
+	}
+}
+
+func changedWords(before, after int) float64 {
+	if before == 0 {
+		return 1
+	}
+	diff := after - before
+	if diff < 0 {
+		diff = -diff
+	}
+	return float64(diff) / float64(before)
+}
+
+// Save writes the history, unless it is nil
+func (h *ChangeHistory) Save() error {
+	if h == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(h.path), 0755); err != nil {
+		return err
+	}
+	data, _ := json.Marshal(h)
+	return os.WriteFile(h.path, data, 0644)
+}
+
+// whatsNewEntry is a page listed on the "what's new" page
+type whatsNewEntry struct {
+	Slug string
+	Date time.Time
+}
+
+// BuildWhatsNewPage lists the pages added or substantially updated within the
+// last cfg.WhatsNewDays days or cfg.WhatsNewBuilds builds, newest first,
+// going by the change history (may be nil) and the frontmatter dates.
+func BuildWhatsNewPage(pages map[string]PageData, history *ChangeHistory, now time.Time) PageData {
+	since := now.AddDate(0, 0, -cfg.WhatsNewDays)
+	recent := func(t time.Time, build int) bool {
+		if cfg.WhatsNewDays > 0 && !t.IsZero() && t.After(since) {
+			return true
+		}
+		return history != nil && cfg.WhatsNewBuilds > 0 && build > 0 && build > history.Builds-cfg.WhatsNewBuilds
+	}
+
+	var added, updated []whatsNewEntry
+	for slug, page := range pages {
+		if !page.Indexed() || page.ListPage > 0 {
+			continue
+		}
+		var change pageChange
+		if history != nil {
+			change = history.Pages[slug]
+		}
+		addedAt, updatedAt := change.Added, change.Updated
+		if !page.PublishedAt.IsZero() && (addedAt.IsZero() || page.PublishedAt.Before(addedAt)) {
+			addedAt = page.PublishedAt
+		}
+		if page.UpdatedAt.After(updatedAt) && page.UpdatedAt.After(page.PublishedAt) {
+			updatedAt = page.UpdatedAt
+		}
+		switch {
+		case recent(addedAt, change.AddedBuild):
+			added = append(added, whatsNewEntry{Slug: slug, Date: addedAt})
+		case recent(updatedAt, change.UpdatedBuild):
+			updated = append(updated, whatsNewEntry{Slug: slug, Date: updatedAt})
+		}
+	}
+
+	var buf strings.Builder
+	var toc []TOCEntry
+	if len(added)+len(updated) == 0 {
+		buf.WriteString("<p>Nothing new lately.</p>\n")
+	}
+	for _, group := range []struct {
+		ID, Title string
+		Entries   []whatsNewEntry
+	}{{"added", "New pages", added}, {"updated", "Updated pages", updated}} {
+		if len(group.Entries) == 0 {
+			continue
+		}
+		sort.Slice(group.Entries, func(i, j int) bool {
+			if !group.Entries[i].Date.Equal(group.Entries[j].Date) {
+				return group.Entries[i].Date.After(group.Entries[j].Date)
+			}
+			return group.Entries[i].Slug < group.Entries[j].Slug
+		})
+		toc = append(toc, TOCEntry{Title: group.Title, ID: group.ID, Level: 2})
+		buf.WriteString(fmt.Sprintf("<h2 id=\"%s\">%s</h2>\n<ul>\n", group.ID, group.Title))
+		for _, e := range group.Entries {
+			page := pages[e.Slug]
+			buf.WriteString(fmt.Sprintf("<li><a href=\"%s\">%s</a> <span class=\"text-sm text-gray-500\">%s</span>", pageHref(e.Slug), html.EscapeString(page.Title), e.Date.Format("2006-01-02")))
+			if page.Description != "" {
+				buf.WriteString("<br>" + html.EscapeString(page.Description))
+			}
+			buf.WriteString("</li>\n")
+		}
+		buf.WriteString("</ul>\n")
+	}
+	return PageData{
+		Title:       "What's New",
+		Content:     buf.String(),
+		TOC:         toc,
+		Description: "Pages added or updated recently.",
+		HideMeta:    true,
+	}
+}
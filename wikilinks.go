@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// wikiLinkAttr marks the anchors of [[wiki links]] rendered by
+// processCustomSyntax, until ResolveWikiLinks points them at their page
+const wikiLinkAttr = "data-wiki-link"
+
+var wikiAnchorRegex = regexp.MustCompile(`<a href="[^"]*" ` + wikiLinkAttr + `="([^"]*)"([^>]*)>(.*?)</a>`)
+
+// wikiTargets finds the page a wiki link names
+type wikiTargets struct {
+	pages     map[string]PageData
+	redirects map[string]string
+	names     map[string][]string // last slug segment -> slugs
+	titles    map[string][]string // lowercased title -> slugs
+}
+
+func newWikiTargets(pages map[string]PageData, redirects map[string]string) *wikiTargets {
+	t := &wikiTargets{pages: pages, redirects: redirects, names: make(map[string][]string), titles: make(map[string][]string)}
+	for _, slug := range sortedKeys(pages) {
+		if slug != "/" {
+			name := path.Base(slug)
+			t.names[name] = append(t.names[name], slug)
+		}
+		title := strings.ToLower(strings.TrimSpace(pages[slug].Title))
+		t.titles[title] = append(t.titles[title], slug)
+	}
+	return t
+}
+
+// find resolves target as a slug (vps/setup), an old path of a page, a file
+// name (setup) or a page title (Mounting the disk), in that order. Names
+// and titles shared by several pages are an error.
+func (t *wikiTargets) find(target string) (string, error) {
+	slug := redirectSlug(strings.TrimSuffix(target, ".md"))
+	if _, ok := t.pages[slug]; ok {
+		return slug, nil
+	}
+	if to, ok := t.redirects[slug]; ok {
+		return to, nil
+	}
+	for _, candidates := range [][]string{
+		t.names[path.Base(strings.Join(strings.Fields(slug), "-"))],
+		t.titles[strings.ToLower(strings.TrimSpace(target))],
+	} {
+		switch len(candidates) {
+		case 0:
+			continue
+		case 1:
+			return candidates[0], nil
+		default:
+			return "", fmt.Errorf("matches %s", strings.Join(candidates, ", "))
+		}
+	}
+	return "", fmt.Errorf("no such page")
+}
+
+// resolve rewrites the wiki links of content, found on the page at from
+// ("" for snippets, where [[#id]] stays relative), and returns the problems
+// of the broken ones, which are left as plain text
+func (t *wikiTargets) resolve(content, from string) (string, []string) {
+	var broken []string
+	content = wikiAnchorRegex.ReplaceAllStringFunc(content, func(m string) string {
+		parts := wikiAnchorRegex.FindStringSubmatch(m)
+		target := html.UnescapeString(parts[1])
+		name, fragment, _ := strings.Cut(target, "#")
+		slug, href := from, ""
+		if name != "" {
+			var err error
+			if slug, err = t.find(name); err != nil {
+				broken = append(broken, fmt.Sprintf("broken wiki link [[%s]]: %v", target, err))
+				return fmt.Sprintf(`<span class="wiki-link-broken" title="Page not found">%s</span>`, parts[3])
+			}
+			href = pageHref(slug)
+		}
+		if fragment != "" {
+			if slug != "" && !strings.Contains(t.pages[slug].Content, ` id="`+html.EscapeString(fragment)+`"`) {
+				broken = append(broken, fmt.Sprintf("broken wiki link [[%s]]: %s has no #%s", target, slug, fragment))
+			}
+			href += "#" + fragment
+		}
+		return fmt.Sprintf(`<a href="%s"%s>%s</a>`, html.EscapeString(href), parts[2], parts[3])
+	})
+	return content, broken
+}
+
+// ResolveWikiLinks points the wiki links of pages and snippets at the pages
+// they name, see wikiTargets.find, reporting the ones that lead nowhere. It
+// returns the number of broken links. Old paths resolve through site.Redirects.
+func ResolveWikiLinks(site *SiteData) int {
+	targets := newWikiTargets(site.Pages, site.Redirects)
+	count := 0
+	report := func(source string, problems []string) {
+		for _, p := range problems {
+			fmt.Printf("Warning: %s: %s\n", source, p)
+		}
+		count += len(problems)
+	}
+	for _, slug := range sortedKeys(site.Pages) {
+		page := site.Pages[slug]
+		content, problems := targets.resolve(page.Content, slug)
+		page.Content = content
+		site.Pages[slug] = page
+		report(slug, problems)
+	}
+	for _, name := range sortedKeys(site.Snippets) {
+		content, problems := targets.resolve(site.Snippets[name], "")
+		site.Snippets[name] = content
+		report(SnippetsDir+"/"+name, problems)
+	}
+	return count
+}